@@ -1,7 +1,22 @@
 package main
 
-import "my_documents_south_backend/internal/app"
+import (
+	"flag"
+	"log"
+	"os"
+
+	"my_documents_south_backend/internal/app"
+	"my_documents_south_backend/internal/config"
+)
 
 func main() {
-	app.Run()
+	configPath := flag.String("config", os.Getenv("MDS_CONFIG"), "path to YAML config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	app.Run(cfg)
 }
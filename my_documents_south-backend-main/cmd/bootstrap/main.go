@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/postgres"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+)
+
+const usage = `usage: bootstrap [-config path] [-role name] [-tariff name] [-email email -name name -last-name name [-middle-name name]]
+
+Creates the super role, the default tariff and the first administrator if they
+do not exist yet. Safe to run repeatedly: existing data is left untouched.
+The administrator password is read from MDS_BOOTSTRAP_PASSWORD or from stdin.
+
+flags:
+`
+
+func main() {
+	configPath := flag.String("config", os.Getenv("MDS_CONFIG"), "path to YAML config file")
+	roleName := flag.String("role", models.DefaultSuperRoleName, "super role name")
+	tariffName := flag.String("tariff", models.DefaultTariffName, "default tariff name")
+	email := flag.String("email", "", "administrator email, empty to skip creating the administrator")
+	name := flag.String("name", "", "administrator first name")
+	lastName := flag.String("last-name", "", "administrator last name")
+	middleName := flag.String("middle-name", "", "administrator middle name")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	bootstrap := models.Bootstrap{RoleName: *roleName, TariffName: *tariffName}
+	if *email != "" {
+		password, err := readPassword()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		bootstrap.Admin = &models.Employee{
+			Name:       *name,
+			LastName:   *lastName,
+			MiddleName: *middleName,
+			Email:      *email,
+			Password:   password,
+		}
+	}
+
+	db := postgres.Connect(cfg.Database)
+	defer db.Close()
+
+	service := services.NewBootstrapService(repository.NewBootstrapRepository(db), cfg.Timeouts.Employee)
+	result, err := service.Run(context.Background(), &bootstrap)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("super role %d: %s\n", result.SuperRoleId, status(result.RoleCreated))
+	fmt.Printf("default tariff %d: %s\n", result.DefaultTariffId, status(result.TariffCreated))
+	switch {
+	case bootstrap.Admin == nil:
+		fmt.Println("administrator: skipped")
+	case result.AdminCreated:
+		fmt.Printf("administrator %d: created\n", result.AdminId)
+	default:
+		fmt.Println("administrator: skipped, employees already exist")
+	}
+}
+
+// readPassword берёт пароль из окружения, иначе читает первую строку stdin
+func readPassword() (string, error) {
+	if password := os.Getenv("MDS_BOOTSTRAP_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "administrator password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func status(created bool) string {
+	if created {
+		return "created"
+	}
+	return "already exists"
+}
@@ -1,28 +1,65 @@
 package app
 
 import (
+	"context"
+	"log"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
 	"my_documents_south_backend/internal/repository/postgres"
+	"my_documents_south_backend/internal/repository/postgres/migrate"
+	"my_documents_south_backend/internal/sms"
+	"my_documents_south_backend/internal/storage"
 	"my_documents_south_backend/internal/transport/rest"
+	"os"
 
 	"github.com/bytedance/sonic"
 	"github.com/gofiber/contrib/swagger"
 	"github.com/gofiber/fiber/v2"
 )
 
-func Run() {
-	app := initFiber()
+func Run(cfg *config.Config) {
+	app := initFiber(cfg)
 	app.Use(initSwagger())
 
-	db := postgres.Connect()
+	db := postgres.Connect(cfg.Database)
 
-	rest.Setup(db, app)
+	if cfg.Database.AutoMigrate {
+		migrator, err := migrate.New(db, os.DirFS(cfg.Database.MigrationsDir))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, migration := range applied {
+			log.Printf("applied migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	smsProvider, err := sms.New(cfg.SMS)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	rest.Setup(db, app, cfg, store, mail, smsProvider)
 
-	if err := app.Listen(":3000"); err != nil {
+	if err := app.Listen(cfg.HTTP.Addr); err != nil {
 		panic(err)
 	}
 }
 
-func initFiber() *fiber.App {
+func initFiber(cfg *config.Config) *fiber.App {
 	unmarshal := func(buf []byte, val interface{}) error {
 		return sonic.Config{DisallowUnknownFields: true}.Froze().Unmarshal(buf, val)
 	}
@@ -31,6 +68,8 @@ func initFiber() *fiber.App {
 		Immutable:   true,
 		JSONEncoder: sonic.Marshal,
 		JSONDecoder: unmarshal,
+		// запас сверх размера файла на заголовки multipart
+		BodyLimit: int(cfg.Storage.MaxUploadSize) + 1<<20,
 	})
 }
 
@@ -0,0 +1,542 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	HTTP              HTTP              `yaml:"http"`
+	Database          Database          `yaml:"database"`
+	JWT               JWT               `yaml:"jwt"`
+	Storage           Storage           `yaml:"storage"`
+	Mail              Mail              `yaml:"mail"`
+	PasswordReset     PasswordReset     `yaml:"password_reset"`
+	EmailVerification EmailVerification `yaml:"email_verification"`
+	SMS               SMS               `yaml:"sms"`
+	OTP               OTP               `yaml:"otp"`
+	MFA               MFA               `yaml:"mfa"`
+	Lockout           Lockout           `yaml:"lockout"`
+	Invite            Invite            `yaml:"invite"`
+	Timeouts          Timeouts          `yaml:"timeouts"`
+}
+
+type HTTP struct {
+	Addr string `yaml:"addr"`
+}
+
+type Database struct {
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	MigrationsDir   string        `yaml:"migrations_dir"`
+	// AutoMigrate применять миграции при старте приложения
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type JWT struct {
+	Secret     string        `yaml:"secret"`
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// Storage хранилище документов заявок
+type Storage struct {
+	Driver           string       `yaml:"driver"`
+	Local            LocalStorage `yaml:"local"`
+	S3               S3Storage    `yaml:"s3"`
+	MaxUploadSize    int64        `yaml:"max_upload_size"`
+	AllowedMimeTypes []string     `yaml:"allowed_mime_types"`
+}
+
+type LocalStorage struct {
+	Root string `yaml:"root"`
+}
+
+type S3Storage struct {
+	Endpoint  string `yaml:"endpoint"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Region    string `yaml:"region"`
+	UseSSL    bool   `yaml:"use_ssl"`
+}
+
+const (
+	MailSMTP = "smtp"
+	MailLog  = "log"
+)
+
+// Mail отправка писем. Драйвер log только пишет письма в лог и подходит для разработки
+type Mail struct {
+	Driver string `yaml:"driver"`
+	From   string `yaml:"from"`
+	SMTP   SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// PasswordReset восстановление пароля по ссылке из письма
+type PasswordReset struct {
+	TTL time.Duration `yaml:"ttl"`
+	// MaxRequests количество писем на один аккаунт за Window
+	MaxRequests int           `yaml:"max_requests"`
+	Window      time.Duration `yaml:"window"`
+	// URL страница восстановления пароля, к ней добавляется параметр token
+	URL string `yaml:"url"`
+}
+
+const (
+	// EmailPolicyNone неподтверждённая почта ничего не ограничивает
+	EmailPolicyNone = "none"
+	// EmailPolicyRequest без подтверждённой почты нельзя создавать заявки
+	EmailPolicyRequest = "request"
+	// EmailPolicyLogin без подтверждённой почты нельзя войти
+	EmailPolicyLogin = "login"
+)
+
+// EmailVerification подтверждение почты по ссылке из письма, отправленного при регистрации
+type EmailVerification struct {
+	TTL time.Duration `yaml:"ttl"`
+	// MaxRequests количество писем на один аккаунт за Window
+	MaxRequests int           `yaml:"max_requests"`
+	Window      time.Duration `yaml:"window"`
+	// URL адрес GET /pub/auth/verify-email, к нему добавляется параметр token
+	URL    string `yaml:"url"`
+	Policy string `yaml:"policy"`
+}
+
+const (
+	SMSHTTP = "http"
+	SMSFile = "file"
+	SMSLog  = "log"
+)
+
+// SMS отправка SMS. Драйверы file и log не отправляют сообщения и подходят для разработки и тестов
+type SMS struct {
+	Driver string  `yaml:"driver"`
+	HTTP   HTTPSMS `yaml:"http"`
+	File   FileSMS `yaml:"file"`
+}
+
+// HTTPSMS HTTP шлюз, принимающий JSON {"to", "text", "sender"}
+type HTTPSMS struct {
+	URL    string `yaml:"url"`
+	Token  string `yaml:"token"`
+	Sender string `yaml:"sender"`
+}
+
+type FileSMS struct {
+	Path string `yaml:"path"`
+}
+
+// OTP одноразовые коды подтверждения телефона и входа по SMS
+type OTP struct {
+	TTL    time.Duration `yaml:"ttl"`
+	Length int           `yaml:"length"`
+	// MaxAttempts количество попыток ввода одного кода
+	MaxAttempts int `yaml:"max_attempts"`
+	// Cooldown минимальный интервал между отправками кода на один номер
+	Cooldown time.Duration `yaml:"cooldown"`
+	// MaxRequests количество отправок кода на один номер за Window
+	MaxRequests int           `yaml:"max_requests"`
+	Window      time.Duration `yaml:"window"`
+}
+
+// MFA двухфакторная аутентификация сотрудников (TOTP)
+type MFA struct {
+	// Issuer название сервиса в приложении-аутентификаторе
+	Issuer string `yaml:"issuer"`
+	// ChallengeTTL время на ввод кода после входа по паролю
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"`
+	// MaxAttempts количество попыток ввода кода на один вход
+	MaxAttempts int `yaml:"max_attempts"`
+	// MaxChallenges количество входов по паролю с ожиданием кода для одного сотрудника за ChallengeWindow
+	MaxChallenges   int           `yaml:"max_challenges"`
+	ChallengeWindow time.Duration `yaml:"challenge_window"`
+}
+
+const (
+	LockoutMemory   = "memory"
+	LockoutPostgres = "postgres"
+)
+
+// Lockout защита входа по паролю от перебора. Неудачные попытки считаются отдельно
+// для IP адреса и для аккаунта, после MaxAttempts попыток за Window вход блокируется
+type Lockout struct {
+	// Store хранилище счётчиков: memory подходит только для одного экземпляра приложения
+	Store              string        `yaml:"store"`
+	Window             time.Duration `yaml:"window"`
+	IPMaxAttempts      int           `yaml:"ip_max_attempts"`
+	AccountMaxAttempts int           `yaml:"account_max_attempts"`
+	// Duration длительность первой блокировки, каждая следующая подряд вдвое дольше, но не дольше MaxDuration
+	Duration    time.Duration `yaml:"duration"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+// Invite приглашения сотрудников
+type Invite struct {
+	// TTL срок действия приглашения, если при создании не указан другой
+	TTL time.Duration `yaml:"ttl"`
+	// URL страница принятия приглашения, к ней добавляется параметр token
+	URL string `yaml:"url"`
+}
+
+// Timeouts таймауты контекста для каждого сервиса
+type Timeouts struct {
+	Role     time.Duration `yaml:"role"`
+	Tariff   time.Duration `yaml:"tariff"`
+	Employee time.Duration `yaml:"employee"`
+	User     time.Duration `yaml:"user"`
+	Request  time.Duration `yaml:"request"`
+	Service  time.Duration `yaml:"service"`
+	Auth     time.Duration `yaml:"auth"`
+	Document time.Duration `yaml:"document"`
+	Chat     time.Duration `yaml:"chat"`
+	Setting  time.Duration `yaml:"setting"`
+	Search   time.Duration `yaml:"search"`
+}
+
+// Default возвращает настройки по умолчанию. Секрет JWT и DSN не имеют значения по умолчанию
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{Addr: ":3000"},
+		Database: Database{
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			MigrationsDir:   "./schemas/migrations",
+		},
+		JWT: JWT{
+			AccessTTL:  time.Hour,
+			RefreshTTL: 7 * 24 * time.Hour,
+		},
+		Storage: Storage{
+			Driver:        StorageLocal,
+			Local:         LocalStorage{Root: "./data/documents"},
+			MaxUploadSize: 20 << 20,
+			AllowedMimeTypes: []string{
+				"application/pdf",
+				"image/jpeg",
+				"image/png",
+				"application/msword",
+				"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				"application/vnd.ms-excel",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			},
+		},
+		Mail: Mail{
+			Driver: MailLog,
+			From:   "no-reply@mydocuments.local",
+			SMTP:   SMTP{Host: "localhost", Port: 1025},
+		},
+		PasswordReset: PasswordReset{
+			TTL:         30 * time.Minute,
+			MaxRequests: 3,
+			Window:      time.Hour,
+			URL:         "http://localhost:5173/password/reset",
+		},
+		EmailVerification: EmailVerification{
+			TTL:         48 * time.Hour,
+			MaxRequests: 3,
+			Window:      time.Hour,
+			URL:         "http://localhost:3000/pub/auth/verify-email",
+			Policy:      EmailPolicyNone,
+		},
+		SMS: SMS{
+			Driver: SMSLog,
+			File:   FileSMS{Path: "./data/sms.log"},
+		},
+		OTP: OTP{
+			TTL:         5 * time.Minute,
+			Length:      6,
+			MaxAttempts: 5,
+			Cooldown:    time.Minute,
+			MaxRequests: 5,
+			Window:      time.Hour,
+		},
+		MFA: MFA{
+			Issuer:          "My Documents South",
+			ChallengeTTL:    5 * time.Minute,
+			MaxAttempts:     5,
+			MaxChallenges:   5,
+			ChallengeWindow: 15 * time.Minute,
+		},
+		Lockout: Lockout{
+			Store:              LockoutPostgres,
+			Window:             15 * time.Minute,
+			IPMaxAttempts:      50,
+			AccountMaxAttempts: 5,
+			Duration:           time.Minute,
+			MaxDuration:        time.Hour,
+		},
+		Invite: Invite{
+			TTL: 72 * time.Hour,
+			URL: "http://localhost:5173/invite",
+		},
+		Timeouts: Timeouts{
+			Role:     10 * time.Second,
+			Tariff:   10 * time.Second,
+			Employee: 10 * time.Second,
+			User:     10 * time.Second,
+			Request:  10 * time.Second,
+			Service:  10 * time.Second,
+			Auth:     10 * time.Second,
+			Document: time.Minute,
+			Chat:     10 * time.Second,
+			Setting:  10 * time.Second,
+			Search:   10 * time.Second,
+		},
+	}
+}
+
+// Load собирает конфигурацию: значения по умолчанию, затем YAML файл (если path не пустой),
+// затем переменные окружения MDS_*. Результат проверяется Validate
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required"))
+	}
+
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn is required"))
+	}
+	if c.Database.MaxOpenConns < 0 {
+		errs = append(errs, errors.New("database.max_open_conns must not be negative"))
+	}
+	if c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database.max_idle_conns must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns must not exceed database.max_open_conns"))
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database.conn_max_lifetime must not be negative"))
+	}
+	if c.Database.MigrationsDir == "" {
+		errs = append(errs, errors.New("database.migrations_dir is required"))
+	}
+
+	if len(c.JWT.Secret) < 32 {
+		errs = append(errs, errors.New("jwt.secret must contain at least 32 characters"))
+	}
+	if c.JWT.AccessTTL <= 0 {
+		errs = append(errs, errors.New("jwt.access_ttl must be positive"))
+	}
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		errs = append(errs, errors.New("jwt.refresh_ttl must be greater than jwt.access_ttl"))
+	}
+
+	switch c.Storage.Driver {
+	case StorageLocal:
+		if c.Storage.Local.Root == "" {
+			errs = append(errs, errors.New("storage.local.root is required"))
+		}
+	case StorageS3:
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" {
+			errs = append(errs, errors.New("storage.s3.endpoint and storage.s3.bucket are required"))
+		}
+		if c.Storage.S3.AccessKey == "" || c.Storage.S3.SecretKey == "" {
+			errs = append(errs, errors.New("storage.s3.access_key and storage.s3.secret_key are required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver must be %q or %q", StorageLocal, StorageS3))
+	}
+	if c.Storage.MaxUploadSize <= 0 {
+		errs = append(errs, errors.New("storage.max_upload_size must be positive"))
+	}
+	if len(c.Storage.AllowedMimeTypes) == 0 {
+		errs = append(errs, errors.New("storage.allowed_mime_types must not be empty"))
+	}
+
+	switch c.Mail.Driver {
+	case MailSMTP:
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port <= 0 {
+			errs = append(errs, errors.New("mail.smtp.host and mail.smtp.port are required"))
+		}
+	case MailLog:
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver must be %q or %q", MailSMTP, MailLog))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from is required"))
+	}
+
+	if c.PasswordReset.TTL <= 0 {
+		errs = append(errs, errors.New("password_reset.ttl must be positive"))
+	}
+	if c.PasswordReset.MaxRequests <= 0 {
+		errs = append(errs, errors.New("password_reset.max_requests must be positive"))
+	}
+	if c.PasswordReset.Window <= 0 {
+		errs = append(errs, errors.New("password_reset.window must be positive"))
+	}
+	if c.PasswordReset.URL == "" {
+		errs = append(errs, errors.New("password_reset.url is required"))
+	}
+
+	if c.EmailVerification.TTL <= 0 {
+		errs = append(errs, errors.New("email_verification.ttl must be positive"))
+	}
+	if c.EmailVerification.MaxRequests <= 0 {
+		errs = append(errs, errors.New("email_verification.max_requests must be positive"))
+	}
+	if c.EmailVerification.Window <= 0 {
+		errs = append(errs, errors.New("email_verification.window must be positive"))
+	}
+	if c.EmailVerification.URL == "" {
+		errs = append(errs, errors.New("email_verification.url is required"))
+	}
+	switch c.EmailVerification.Policy {
+	case EmailPolicyNone, EmailPolicyRequest, EmailPolicyLogin:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"email_verification.policy must be %q, %q or %q",
+			EmailPolicyNone,
+			EmailPolicyRequest,
+			EmailPolicyLogin,
+		))
+	}
+
+	switch c.SMS.Driver {
+	case SMSHTTP:
+		if c.SMS.HTTP.URL == "" {
+			errs = append(errs, errors.New("sms.http.url is required"))
+		}
+	case SMSFile:
+		if c.SMS.File.Path == "" {
+			errs = append(errs, errors.New("sms.file.path is required"))
+		}
+	case SMSLog:
+	default:
+		errs = append(errs, fmt.Errorf("sms.driver must be %q, %q or %q", SMSHTTP, SMSFile, SMSLog))
+	}
+
+	if c.OTP.TTL <= 0 {
+		errs = append(errs, errors.New("otp.ttl must be positive"))
+	}
+	if c.OTP.Length < 4 || c.OTP.Length > 10 {
+		errs = append(errs, errors.New("otp.length must be between 4 and 10"))
+	}
+	if c.OTP.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("otp.max_attempts must be positive"))
+	}
+	if c.OTP.Cooldown < 0 {
+		errs = append(errs, errors.New("otp.cooldown must not be negative"))
+	}
+	if c.OTP.MaxRequests <= 0 {
+		errs = append(errs, errors.New("otp.max_requests must be positive"))
+	}
+	if c.OTP.Window <= 0 {
+		errs = append(errs, errors.New("otp.window must be positive"))
+	}
+
+	if c.MFA.Issuer == "" {
+		errs = append(errs, errors.New("mfa.issuer is required"))
+	}
+	if c.MFA.ChallengeTTL <= 0 {
+		errs = append(errs, errors.New("mfa.challenge_ttl must be positive"))
+	}
+	if c.MFA.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("mfa.max_attempts must be positive"))
+	}
+	if c.MFA.MaxChallenges <= 0 {
+		errs = append(errs, errors.New("mfa.max_challenges must be positive"))
+	}
+	if c.MFA.ChallengeWindow <= 0 {
+		errs = append(errs, errors.New("mfa.challenge_window must be positive"))
+	}
+
+	if c.Lockout.Store != LockoutMemory && c.Lockout.Store != LockoutPostgres {
+		errs = append(errs, fmt.Errorf("lockout.store must be %q or %q", LockoutMemory, LockoutPostgres))
+	}
+	if c.Lockout.Window <= 0 {
+		errs = append(errs, errors.New("lockout.window must be positive"))
+	}
+	if c.Lockout.IPMaxAttempts <= 0 {
+		errs = append(errs, errors.New("lockout.ip_max_attempts must be positive"))
+	}
+	if c.Lockout.AccountMaxAttempts <= 0 {
+		errs = append(errs, errors.New("lockout.account_max_attempts must be positive"))
+	}
+	if c.Lockout.Duration <= 0 {
+		errs = append(errs, errors.New("lockout.duration must be positive"))
+	}
+	if c.Lockout.MaxDuration < c.Lockout.Duration {
+		errs = append(errs, errors.New("lockout.max_duration must not be less than lockout.duration"))
+	}
+
+	if c.Invite.TTL <= 0 {
+		errs = append(errs, errors.New("invite.ttl must be positive"))
+	}
+	if c.Invite.URL == "" {
+		errs = append(errs, errors.New("invite.url is required"))
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"role", c.Timeouts.Role},
+		{"tariff", c.Timeouts.Tariff},
+		{"employee", c.Timeouts.Employee},
+		{"user", c.Timeouts.User},
+		{"request", c.Timeouts.Request},
+		{"service", c.Timeouts.Service},
+		{"auth", c.Timeouts.Auth},
+		{"document", c.Timeouts.Document},
+		{"chat", c.Timeouts.Chat},
+		{"setting", c.Timeouts.Setting},
+		{"search", c.Timeouts.Search},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("timeouts.%s must be positive", timeout.name))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv переопределяет значения конфигурации переменными окружения
+func applyEnv(cfg *Config) error {
+	strs := map[string]*string{
+		"MDS_HTTP_ADDR":                 &cfg.HTTP.Addr,
+		"MDS_DB_DSN":                    &cfg.Database.DSN,
+		"MDS_DB_MIGRATIONS_DIR":         &cfg.Database.MigrationsDir,
+		"MDS_JWT_SECRET":                &cfg.JWT.Secret,
+		"MDS_STORAGE_DRIVER":            &cfg.Storage.Driver,
+		"MDS_STORAGE_ROOT":              &cfg.Storage.Local.Root,
+		"MDS_S3_ENDPOINT":               &cfg.Storage.S3.Endpoint,
+		"MDS_S3_BUCKET":                 &cfg.Storage.S3.Bucket,
+		"MDS_S3_ACCESS_KEY":             &cfg.Storage.S3.AccessKey,
+		"MDS_S3_SECRET_KEY":             &cfg.Storage.S3.SecretKey,
+		"MDS_S3_REGION":                 &cfg.Storage.S3.Region,
+		"MDS_MAIL_DRIVER":               &cfg.Mail.Driver,
+		"MDS_MAIL_FROM":                 &cfg.Mail.From,
+		"MDS_SMTP_HOST":                 &cfg.Mail.SMTP.Host,
+		"MDS_SMTP_USERNAME":             &cfg.Mail.SMTP.Username,
+		"MDS_SMTP_PASSWORD":             &cfg.Mail.SMTP.Password,
+		"MDS_PASSWORD_RESET_URL":        &cfg.PasswordReset.URL,
+		"MDS_EMAIL_VERIFICATION_URL":    &cfg.EmailVerification.URL,
+		"MDS_EMAIL_VERIFICATION_POLICY": &cfg.EmailVerification.Policy,
+		"MDS_MFA_ISSUER":                &cfg.MFA.Issuer,
+		"MDS_LOCKOUT_STORE":             &cfg.Lockout.Store,
+		"MDS_INVITE_URL":                &cfg.Invite.URL,
+		"MDS_SMS_DRIVER":                &cfg.SMS.Driver,
+		"MDS_SMS_HTTP_URL":              &cfg.SMS.HTTP.URL,
+		"MDS_SMS_HTTP_TOKEN":            &cfg.SMS.HTTP.Token,
+		"MDS_SMS_HTTP_SENDER":           &cfg.SMS.HTTP.Sender,
+		"MDS_SMS_FILE_PATH":             &cfg.SMS.File.Path,
+	}
+	for key, dst := range strs {
+		if value, ok := os.LookupEnv(key); ok {
+			*dst = value
+		}
+	}
+
+	bools := map[string]*bool{
+		"MDS_DB_AUTO_MIGRATE": &cfg.Database.AutoMigrate,
+		"MDS_S3_USE_SSL":      &cfg.Storage.S3.UseSSL,
+	}
+	for key, dst := range bools {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = parsed
+	}
+
+	ints := map[string]*int{
+		"MDS_DB_MAX_OPEN_CONNS":               &cfg.Database.MaxOpenConns,
+		"MDS_DB_MAX_IDLE_CONNS":               &cfg.Database.MaxIdleConns,
+		"MDS_SMTP_PORT":                       &cfg.Mail.SMTP.Port,
+		"MDS_PASSWORD_RESET_MAX_REQUESTS":     &cfg.PasswordReset.MaxRequests,
+		"MDS_EMAIL_VERIFICATION_MAX_REQUESTS": &cfg.EmailVerification.MaxRequests,
+		"MDS_MFA_MAX_ATTEMPTS":                &cfg.MFA.MaxAttempts,
+		"MDS_MFA_MAX_CHALLENGES":              &cfg.MFA.MaxChallenges,
+		"MDS_LOCKOUT_IP_MAX_ATTEMPTS":         &cfg.Lockout.IPMaxAttempts,
+		"MDS_LOCKOUT_ACCOUNT_MAX_ATTEMPTS":    &cfg.Lockout.AccountMaxAttempts,
+		"MDS_OTP_LENGTH":                      &cfg.OTP.Length,
+		"MDS_OTP_MAX_ATTEMPTS":                &cfg.OTP.MaxAttempts,
+		"MDS_OTP_MAX_REQUESTS":                &cfg.OTP.MaxRequests,
+	}
+	for key, dst := range ints {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = parsed
+	}
+
+	if value, ok := os.LookupEnv("MDS_STORAGE_MAX_UPLOAD_SIZE"); ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid MDS_STORAGE_MAX_UPLOAD_SIZE: %w", err)
+		}
+		cfg.Storage.MaxUploadSize = parsed
+	}
+
+	if value, ok := os.LookupEnv("MDS_STORAGE_ALLOWED_MIME_TYPES"); ok {
+		cfg.Storage.AllowedMimeTypes = nil
+		for _, mimeType := range strings.Split(value, ",") {
+			if mimeType = strings.TrimSpace(mimeType); mimeType != "" {
+				cfg.Storage.AllowedMimeTypes = append(cfg.Storage.AllowedMimeTypes, mimeType)
+			}
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"MDS_DB_CONN_MAX_LIFETIME":      &cfg.Database.ConnMaxLifetime,
+		"MDS_JWT_ACCESS_TTL":            &cfg.JWT.AccessTTL,
+		"MDS_JWT_REFRESH_TTL":           &cfg.JWT.RefreshTTL,
+		"MDS_PASSWORD_RESET_TTL":        &cfg.PasswordReset.TTL,
+		"MDS_PASSWORD_RESET_WINDOW":     &cfg.PasswordReset.Window,
+		"MDS_EMAIL_VERIFICATION_TTL":    &cfg.EmailVerification.TTL,
+		"MDS_EMAIL_VERIFICATION_WINDOW": &cfg.EmailVerification.Window,
+		"MDS_MFA_CHALLENGE_TTL":         &cfg.MFA.ChallengeTTL,
+		"MDS_MFA_CHALLENGE_WINDOW":      &cfg.MFA.ChallengeWindow,
+		"MDS_LOCKOUT_WINDOW":            &cfg.Lockout.Window,
+		"MDS_LOCKOUT_DURATION":          &cfg.Lockout.Duration,
+		"MDS_LOCKOUT_MAX_DURATION":      &cfg.Lockout.MaxDuration,
+		"MDS_INVITE_TTL":                &cfg.Invite.TTL,
+		"MDS_OTP_TTL":                   &cfg.OTP.TTL,
+		"MDS_OTP_COOLDOWN":              &cfg.OTP.Cooldown,
+		"MDS_OTP_WINDOW":                &cfg.OTP.Window,
+		"MDS_TIMEOUT_ROLE":              &cfg.Timeouts.Role,
+		"MDS_TIMEOUT_TARIFF":            &cfg.Timeouts.Tariff,
+		"MDS_TIMEOUT_EMPLOYEE":          &cfg.Timeouts.Employee,
+		"MDS_TIMEOUT_USER":              &cfg.Timeouts.User,
+		"MDS_TIMEOUT_REQUEST":           &cfg.Timeouts.Request,
+		"MDS_TIMEOUT_SERVICE":           &cfg.Timeouts.Service,
+		"MDS_TIMEOUT_AUTH":              &cfg.Timeouts.Auth,
+		"MDS_TIMEOUT_DOCUMENT":          &cfg.Timeouts.Document,
+		"MDS_TIMEOUT_CHAT":              &cfg.Timeouts.Chat,
+		"MDS_TIMEOUT_SETTING":           &cfg.Timeouts.Setting,
+		"MDS_TIMEOUT_SEARCH":            &cfg.Timeouts.Search,
+	}
+	for key, dst := range durations {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = parsed
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer пишет письма в лог вместо отправки
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(_ context.Context, message Message) error {
+	log.Printf("mail: to=%s subject=%q\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
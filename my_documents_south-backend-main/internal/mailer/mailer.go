@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"my_documents_south_backend/internal/config"
+)
+
+// Message письмо в формате text/plain
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма пользователям
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New создаёт отправителя писем по настройкам mail.driver
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Driver {
+	case config.MailSMTP:
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	case config.MailLog:
+		return NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
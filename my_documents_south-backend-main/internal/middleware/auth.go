@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"errors"
+	"my_documents_south_backend/internal/models"
 	"time"
 
 	jwtware "github.com/gofiber/contrib/jwt"
@@ -8,24 +10,77 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTGenerate(userID int64, roleID *int, expiresIn time.Duration) (string, error) {
+type TokenType string
+
+const (
+	TokenAccess  TokenType = "access"
+	TokenRefresh TokenType = "refresh"
+)
+
+// JWTGenerate подписывает токен для клиента или сотрудника. Тип субъекта хранится в claim pty,
+// так как id клиентов и сотрудников пересекаются. Роль передаётся только для сотрудников.
+// Claim sid связывает токен с сессией (семейством refresh токенов), jti уникален для каждого токена
+func JWTGenerate(secret string, tokenType TokenType, principal *models.Principal, jti string, expiresIn time.Duration) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
-	claims["sub"] = userID
-	if roleID != nil {
-		claims["role"] = roleID
+	claims["typ"] = string(tokenType)
+	claims["sub"] = principal.Id
+	claims["pty"] = string(principal.Type)
+	if principal.RoleId != nil {
+		claims["role"] = *principal.RoleId
 	}
+	claims["sid"] = principal.SessionId
+	claims["jti"] = jti
 	claims["iat"] = time.Now().Unix()
 	claims["exp"] = time.Now().Add(expiresIn).Unix()
 
-	return token.SignedString([]byte("my_documents_south_jwt_super_secret_key_for_security"))
+	return token.SignedString([]byte(secret))
+}
+
+// ParseRefreshToken проверяет подпись и тип refresh токена и возвращает его субъект и jti
+func ParseRefreshToken(secret string, tokenString string) (*models.Principal, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != string(TokenRefresh) {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+
+	principal, err := principalFromClaims(claims)
+	if err != nil || principal.SessionId == "" {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+
+	return principal, jti, nil
 }
 
 // Protected protect routes
-func Protected() fiber.Handler {
+func Protected(secret string) fiber.Handler {
+	return protected(secret, "")
+}
+
+// ProtectedWS protect WebSocket routes. Браузер не может передать заголовок Authorization
+// при открытии WebSocket, поэтому токен также принимается в параметре token
+func ProtectedWS(secret string) fiber.Handler {
+	return protected(secret, "header:Authorization,query:token")
+}
+
+func protected(secret string, tokenLookup string) fiber.Handler {
 	return jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{Key: []byte("my_documents_south_jwt_super_secret_key_for_security")},
+		SigningKey:  jwtware.SigningKey{Key: []byte(secret)},
+		TokenLookup: tokenLookup,
+		AuthScheme:  "Bearer",
 		SuccessHandler: func(c *fiber.Ctx) error {
 			// Получаем токен из контекста
 			token := c.Locals("user").(*jwt.Token)
@@ -38,32 +93,73 @@ func Protected() fiber.Handler {
 				})
 			}
 
-			// Извлекаем userID из claims
-			userID, ok := claims["sub"].(float64) // JWT хранит числа как float64
-			if !ok {
+			// refresh токен не даёт доступа к защищённым маршрутам
+			if claims["typ"] != string(TokenAccess) {
 				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 					"status":  "error",
-					"message": "Invalid user ID in token",
+					"message": "Invalid token type",
 					"data":    nil,
 				})
 			}
 
-			// Сохраняем userID в контексте для последующих обработчиков
-			c.Locals("userID", int64(userID))
-
-			// Извлекаем userID из claims
-			roleID, ok := claims["role"].(float64) // JWT хранит числа как float64
-			if ok {
-				// Сохраняем userID в контексте для последующих обработчиков
-				c.Locals("roleID", int(roleID))
+			principal, err := principalFromClaims(claims)
+			if err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"status":  "error",
+					"message": err.Error(),
+					"data":    nil,
+				})
 			}
 
+			// Сохраняем principal в контексте для последующих обработчиков
+			c.Locals("principal", principal)
+
 			return c.Next()
 		},
 		ErrorHandler: jwtError,
 	})
 }
 
+// principalFromClaims восстанавливает инициатора запроса из claims.
+// Токен сотрудника обязан содержать роль, токен клиента — не содержать её
+func principalFromClaims(claims jwt.MapClaims) (*models.Principal, error) {
+	id, ok := claims["sub"].(float64) // JWT хранит числа как float64
+	if !ok {
+		return nil, errors.New("Invalid user ID in token")
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	principal := &models.Principal{Id: int64(id), SessionId: sessionID}
+
+	pty, _ := claims["pty"].(string)
+	role, hasRole := claims["role"].(float64)
+
+	switch models.ActorType(pty) {
+	case models.ActorEmployee:
+		if !hasRole {
+			return nil, errors.New("Invalid role in token")
+		}
+		roleID := int(role)
+		principal.Type = models.ActorEmployee
+		principal.RoleId = &roleID
+	case models.ActorUser:
+		if hasRole {
+			return nil, errors.New("Invalid role in token")
+		}
+		principal.Type = models.ActorUser
+	default:
+		return nil, errors.New("Invalid principal type in token")
+	}
+
+	return principal, nil
+}
+
+// PrincipalFromCtx возвращает инициатора запроса, сохранённого Protected
+func PrincipalFromCtx(c *fiber.Ctx) (*models.Principal, bool) {
+	principal, ok := c.Locals("principal").(*models.Principal)
+	return principal, ok
+}
+
 func jwtError(c *fiber.Ctx, err error) error {
 	if err.Error() == "Missing or malformed JWT" {
 		return c.Status(fiber.StatusBadRequest).
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PermissionProvider interface {
+	GetPermissions(c context.Context, id int) (*models.RolePermissions, error)
+}
+
+// Authorize загружает права роли сотрудника в Principal. Должен стоять после Protected.
+// У клиентов прав нет, доступ к их данным проверяется в обработчиках
+func Authorize(provider PermissionProvider) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := PrincipalFromCtx(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"status": "error", "message": "Missing principal", "data": nil})
+		}
+
+		principal.Permissions = models.NewPermissionSet()
+		if principal.IsEmployee() {
+			rolePermissions, err := provider.GetPermissions(c.Context(), *principal.RoleId)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).
+					JSON(fiber.Map{"status": "error", "message": "Failed to load permissions", "data": nil})
+			}
+			principal.Permissions = rolePermissions.Set()
+			principal.Super = rolePermissions.Super
+		}
+
+		return c.Next()
+	}
+}
+
+// Require пропускает запрос, только если у роли есть все перечисленные права
+func Require(permissions ...models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, permission := range permissions {
+			if !HasPermission(c, permission) {
+				return c.Status(fiber.StatusForbidden).
+					JSON(fiber.Map{"status": "error", "message": "Insufficient permissions", "data": string(permission)})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// RequireSuperRole пропускает только сотрудников с суперролью
+func RequireSuperRole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := PrincipalFromCtx(c)
+		if !ok || !principal.Super {
+			return c.Status(fiber.StatusForbidden).
+				JSON(fiber.Map{"status": "error", "message": "Super role required", "data": nil})
+		}
+		return c.Next()
+	}
+}
+
+func HasPermission(c *fiber.Ctx, permission models.Permission) bool {
+	principal, ok := PrincipalFromCtx(c)
+	return ok && principal.Can(permission)
+}
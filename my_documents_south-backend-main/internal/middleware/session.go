@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionChecker interface {
+	IsActive(c context.Context, id string) (bool, error)
+}
+
+// ActiveSession отклоняет токены завершённых сессий, чтобы выход и завершение сессии
+// действовали сразу, а не после истечения access токена. Должен стоять после Protected
+func ActiveSession(checker SessionChecker, timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := PrincipalFromCtx(c)
+		if !ok || principal.SessionId == "" {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"status": "error", "message": "Invalid or expired JWT", "data": nil})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), timeout)
+		defer cancel()
+
+		active, err := checker.IsActive(ctx, principal.SessionId)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).
+				JSON(fiber.Map{"status": "error", "message": "Failed to check session", "data": nil})
+		}
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"status": "error", "message": "Session is terminated", "data": nil})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import "errors"
+
+var (
+	ErrForbidden = errors.New("access denied")
+	// ErrWrongPassword неверный текущий пароль при его смене
+	ErrWrongPassword = errors.New("current password is incorrect")
+)
+
+type ActorType string
+
+const (
+	ActorUser     ActorType = "user"
+	ActorEmployee ActorType = "employee"
+)
+
+// Actor инициатор действия: клиент (user) или сотрудник (employee)
+type Actor struct {
+	Type ActorType `json:"type" db:"type"`
+	Id   int64     `json:"id" db:"id"`
+}
+
+func (a Actor) IsEmployee() bool {
+	return a.Type == ActorEmployee
+}
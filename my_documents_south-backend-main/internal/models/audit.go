@@ -0,0 +1,28 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type AuditEventType string
+
+const (
+	// AuditLoginLockout вход заблокирован после серии неудачных попыток
+	AuditLoginLockout AuditEventType = "login_lockout"
+)
+
+// AuditEvent запись журнала событий безопасности. Actor не заполняется, если инициатор неизвестен
+type AuditEvent struct {
+	Id        int64           `json:"id" db:"id"`
+	Type      AuditEventType  `json:"type" db:"type"`
+	Actor     *Actor          `json:"actor,omitempty" db:"-"`
+	Ip        string          `json:"ip,omitempty" db:"ip"`
+	Details   json.RawMessage `json:"details" db:"details"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, event *AuditEvent) error
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	DefaultSuperRoleName = "Администратор"
+	DefaultTariffName    = "Базовый"
+)
+
+// ErrBootstrapClosed первый сотрудник уже создан, остальные добавляются по приглашениям
+var ErrBootstrapClosed = errors.New("bootstrap is only available while there are no employees")
+
+// Bootstrap первоначальная настройка: суперроль, тариф по умолчанию и первый администратор.
+// Роль и тариф создаются, только если в setting они не заданы, администратор — только пока нет сотрудников
+type Bootstrap struct {
+	RoleName   string
+	TariffName string
+	// Admin первый сотрудник с суперролью, nil — не создавать. Пароль шифруется сервисом до записи
+	Admin *Employee
+	// Strict вернуть ErrBootstrapClosed и ничего не менять, если сотрудники уже есть
+	Strict bool
+}
+
+type BootstrapResult struct {
+	SuperRoleId     int   `json:"super_role_id"`
+	RoleCreated     bool  `json:"role_created"`
+	DefaultTariffId int   `json:"default_tariff_id"`
+	TariffCreated   bool  `json:"tariff_created"`
+	AdminId         int64 `json:"admin_id,omitempty"`
+	AdminCreated    bool  `json:"admin_created"`
+}
+
+type BootstrapRepository interface {
+	// Run выполняет первоначальную настройку в одной транзакции. Повторный запуск ничего не меняет
+	Run(ctx context.Context, bootstrap *Bootstrap, result *BootstrapResult) error
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrDocumentTooLarge    = errors.New("document is too large")
+	ErrDocumentType        = errors.New("document type is not allowed")
+	ErrDocumentNotFound    = errors.New("document not found")
+	ErrDocumentInvalidName = errors.New("invalid document name")
+)
+
+// Document метаданные файла, прикреплённого к заявке. Содержимое хранится в BlobStore
+type Document struct {
+	Id         int64     `json:"id" db:"id"`
+	RequestId  int64     `json:"request_id" db:"request_id"`
+	Name       string    `json:"name" db:"name"`
+	MimeType   string    `json:"mime_type" db:"mime_type"`
+	Size       int64     `json:"size" db:"size"`
+	Checksum   string    `json:"checksum" db:"checksum"`
+	StorageKey string    `json:"-" db:"storage_key"`
+	UploadedBy Actor     `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// DocumentUpload загружаемый файл
+type DocumentUpload struct {
+	Name    string
+	Size    int64
+	Content io.Reader
+}
+
+type DocumentRepository interface {
+	Create(ctx context.Context, document *Document) error
+	GetById(ctx context.Context, requestId int64, id int64, document *Document) error
+	GetByRequest(ctx context.Context, requestId int64, documents *[]Document) error
+	Delete(ctx context.Context, requestId int64, id int64) error
+}
+
+type DocumentService interface {
+	Upload(ctx context.Context, requestId int64, upload DocumentUpload, actor Actor) (*Document, error)
+	GetByRequest(ctx context.Context, requestId int64) ([]Document, error)
+	Open(ctx context.Context, requestId int64, id int64) (*Document, io.ReadCloser, error)
+	Delete(ctx context.Context, requestId int64, id int64, actor Actor) error
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+	ErrEmailNotVerified         = errors.New("email is not verified")
+)
+
+// EmailVerification токен подтверждения почты. Хранится только SHA-256 хеш токена.
+// Email запоминается, чтобы токен не подтвердил почту, изменённую после отправки письма
+type EmailVerification struct {
+	Id        int64      `db:"id"`
+	Subject   Actor      `db:"subject"`
+	Email     string     `db:"email"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, verification *EmailVerification) error
+	// CountSince количество токенов субъекта, выданных после since
+	CountSince(ctx context.Context, subject Actor, since time.Time) (int, error)
+	// Verify в одной транзакции помечает токен использованным и почту субъекта подтверждённой.
+	// Если токен не найден, использован, истёк или почта изменилась, возвращает ErrInvalidVerificationToken
+	Verify(ctx context.Context, tokenHash string) (Actor, error)
+	IsVerified(ctx context.Context, subject Actor) (bool, error)
+}
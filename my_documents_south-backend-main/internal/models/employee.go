@@ -2,10 +2,19 @@ package models
 
 import (
 	"context"
+	"errors"
 	"my_documents_south_backend/internal/interfaces"
 	"time"
 )
 
+var (
+	ErrEmployeeNotFound = errors.New("employee not found")
+	ErrInvalidEmployee  = errors.New("invalid employee")
+	ErrEmployeeInactive = errors.New("employee is deactivated")
+	// ErrEmployeeServiceNotFound услуга не назначена сотруднику
+	ErrEmployeeServiceNotFound = errors.New("service is not assigned to the employee")
+)
+
 type Employee struct {
 	Id         int64  `json:"id,omitempty" db:"id"`
 	Name       string `json:"name,omitempty" db:"name"`
@@ -14,29 +23,64 @@ type Employee struct {
 	Email      string `json:"email,omitempty" db:"email"`
 	Password   string `json:"password,omitempty" db:"password"`
 
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+
 	RoleId int   `json:"role_id,omitempty" db:"role_id"`
 	Role   *Role `json:"role,omitempty" db:"role"`
 
 	Services []Service `json:"services,omitempty" db:"services"`
 
-	Active    bool       `json:"active,omitempty" db:"active"`
+	Active    bool       `json:"active" db:"active"`
 	CreatedAt time.Time  `json:"created_at,omitempty" db:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 }
 
+// EmployeeFilter фильтр списка сотрудников, nil поля не ограничивают выборку
+type EmployeeFilter struct {
+	RoleId    *int
+	ServiceId *int
+	Active    *bool
+}
+
+// EmployeeUpdate частичное изменение сотрудника, nil поля не меняются
+type EmployeeUpdate struct {
+	Name       *string `json:"name"`
+	LastName   *string `json:"last_name"`
+	MiddleName *string `json:"middle_name"`
+	Email      *string `json:"email"`
+	RoleId     *int    `json:"role_id"`
+}
+
+// EmployeeRepository методы чтения не возвращают хеш пароля, кроме GetByEmail и GetPassword
 type EmployeeRepository interface {
 	interfaces.EntityRepository[Employee]
 	GetByEmail(c context.Context, email string, employee *Employee) error
+	GetByIds(c context.Context, ids []int64, employees *[]Employee) error
+	GetPassword(c context.Context, id int64) (string, error)
+	// SetPassword сохраняет новый хеш пароля
+	SetPassword(c context.Context, id int64, hash string) error
+	SetActive(c context.Context, id int64, active bool) error
 	AddService(ctx context.Context, id int64, id2 int) error
 	RemoveService(ctx context.Context, id int64, id2 int) error
 	GetByIdWithServices(ctx context.Context, id int64) (*Employee, error)
-	GetAllWithServices(ctx context.Context) ([]Employee, error)
+	GetAllWithServices(ctx context.Context, filter EmployeeFilter) ([]Employee, error)
 }
 
 type EmployeeService interface {
-	interfaces.EntityService[Employee]
+	Create(c context.Context, employee *Employee) error
+	Get(c context.Context) *[]Employee
+	GetById(c context.Context, id int) (*Employee, error)
+	Update(c context.Context, id int, employee *Employee) error
+	// Delete удаляет сотрудника, чью роль actor может выдать. Удалить себя нельзя
+	Delete(c context.Context, actor *Principal, id int64) error
+	// Patch меняет только переданные поля. Изменять можно только сотрудников, чью роль actor может выдать
+	Patch(ctx context.Context, actor *Principal, id int64, update EmployeeUpdate) (*Employee, error)
+	// ChangePassword меняет пароль сотрудника principal после проверки текущего и завершает его остальные сессии
+	ChangePassword(ctx context.Context, principal *Principal, current string, next string) error
+	// SetActive деактивирует или восстанавливает сотрудника. Деактивация завершает все его сессии
+	SetActive(ctx context.Context, actor *Principal, id int64, active bool) (*Employee, error)
 	AddService(ctx context.Context, id int64, id2 int) error
 	RemoveService(ctx context.Context, id int64, id2 int) error
 	GetByIdWithServices(ctx context.Context, id int64) (*Employee, error)
-	GetAllWithServices(ctx context.Context) ([]Employee, error)
+	GetAllWithServices(ctx context.Context, filter EmployeeFilter) ([]Employee, error)
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidInvite      = errors.New("invalid invite")
+	ErrInvalidInviteToken = errors.New("invalid or expired invite token")
+	ErrInviteNotFound     = errors.New("invite not found")
+	ErrEmployeeExists     = errors.New("employee with this email already exists")
+)
+
+// EmployeeInvite приглашение сотрудника. Хранится только SHA-256 хеш токена из письма.
+// Имя можно указать заранее, приглашённый может изменить его при принятии
+type EmployeeInvite struct {
+	Id         int64      `json:"id" db:"id"`
+	Email      string     `json:"email" db:"email"`
+	RoleId     int        `json:"role_id" db:"role_id"`
+	RoleName   string     `json:"role_name,omitempty" db:"role_name"`
+	Name       *string    `json:"name,omitempty" db:"name"`
+	LastName   *string    `json:"last_name,omitempty" db:"last_name"`
+	MiddleName *string    `json:"middle_name,omitempty" db:"middle_name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	InvitedBy  *int64     `json:"invited_by,omitempty" db:"invited_by"`
+	EmployeeId *int64     `json:"employee_id,omitempty" db:"employee_id"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"-" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type InviteRepository interface {
+	// Create сохраняет приглашение и отзывает прежние неиспользованные приглашения на ту же почту.
+	// Если сотрудник с такой почтой уже есть, возвращает ErrEmployeeExists
+	Create(ctx context.Context, invite *EmployeeInvite) error
+	// GetPending возвращает действующие приглашения, новые первыми
+	GetPending(ctx context.Context, invites *[]EmployeeInvite) error
+	// GetByToken возвращает действующее приглашение или ErrInvalidInviteToken
+	GetByToken(ctx context.Context, tokenHash string, invite *EmployeeInvite) error
+	Revoke(ctx context.Context, id int64) error
+	// Accept создаёт сотрудника с почтой и ролью из приглашения и отмечает приглашение использованным.
+	// Почта сотрудника считается подтверждённой: токен пришёл на неё
+	Accept(ctx context.Context, tokenHash string, employee *Employee) error
+}
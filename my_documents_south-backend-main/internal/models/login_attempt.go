@@ -0,0 +1,26 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// LoginLockout блокировка входа по ключу. Level номер блокировки подряд, от него зависит её длительность
+type LoginLockout struct {
+	Key         string    `db:"key"`
+	LockedUntil time.Time `db:"locked_until"`
+	Level       int       `db:"level"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// LoginAttemptStore счётчики неудачных попыток входа и блокировки. Ключ — IP адрес или аккаунт
+type LoginAttemptStore interface {
+	// GetLockout возвращает последнюю блокировку ключа. Если ключ не блокировался, lockout не меняется
+	GetLockout(ctx context.Context, key string, lockout *LoginLockout) error
+	// AddFailure сохраняет неудачную попытку и возвращает количество попыток ключа после since
+	AddFailure(ctx context.Context, key string, since time.Time) (int, error)
+	// Lock блокирует ключ до until с уровнем level и сбрасывает его попытки
+	Lock(ctx context.Context, key string, until time.Time, level int) error
+	// Reset удаляет попытки и блокировку ключа
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidMessage = errors.New("invalid message: must contain from 1 to 4000 characters")
+
+// Message сообщение в чате заявки между клиентом и назначенным сотрудником
+type Message struct {
+	Id        int64      `json:"id" db:"id"`
+	RequestId int64      `json:"request_id" db:"request_id"`
+	Sender    Actor      `json:"sender" db:"sender"`
+	Body      string     `json:"body" db:"body"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+}
+
+// MessagePage страница истории сообщений. NextCursor передаётся в cursor для загрузки более старых сообщений
+type MessagePage struct {
+	Items      []Message `json:"items"`
+	NextCursor *int64    `json:"next_cursor"`
+}
+
+type ChatEventType string
+
+const (
+	ChatEventMessage ChatEventType = "message"
+	ChatEventTyping  ChatEventType = "typing"
+	ChatEventRead    ChatEventType = "read"
+	ChatEventError   ChatEventType = "error"
+)
+
+// ChatEvent событие, рассылаемое участникам чата по WebSocket
+type ChatEvent struct {
+	Type      ChatEventType `json:"type"`
+	RequestId int64         `json:"request_id"`
+	Actor     *Actor        `json:"actor,omitempty"`
+	Message   *Message      `json:"message,omitempty"`
+	UpTo      int64         `json:"up_to,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ChatParticipants владелец заявки и назначенный сотрудник
+type ChatParticipants struct {
+	OwnerId    int64  `db:"owner_id"`
+	EmployeeId *int64 `db:"employee_id"`
+}
+
+func (p ChatParticipants) Has(actor Actor) bool {
+	if actor.IsEmployee() {
+		return p.EmployeeId != nil && *p.EmployeeId == actor.Id
+	}
+	return p.OwnerId == actor.Id
+}
+
+type MessageRepository interface {
+	Create(ctx context.Context, message *Message) error
+	GetPage(ctx context.Context, requestId int64, before int64, limit int, messages *[]Message) error
+	MarkRead(ctx context.Context, requestId int64, reader Actor, upTo int64) (int64, error)
+	GetParticipants(ctx context.Context, requestId int64, participants *ChatParticipants) error
+}
+
+type ChatService interface {
+	Authorize(ctx context.Context, requestId int64, actor Actor) error
+	History(ctx context.Context, requestId int64, actor Actor, cursor int64, limit int) (*MessagePage, error)
+	Send(ctx context.Context, requestId int64, actor Actor, body string) (*Message, error)
+	MarkRead(ctx context.Context, requestId int64, actor Actor, upTo int64) error
+	Typing(ctx context.Context, requestId int64, actor Actor) error
+	Subscribe(ctx context.Context, requestId int64, actor Actor) (*ChatSubscription, error)
+}
+
+// ChatSubscription подписка на события чата заявки. Close должен быть вызван после использования.
+// Events закрывается, если подписчик перестал быть участником чата
+type ChatSubscription struct {
+	Events <-chan ChatEvent
+	Close  func()
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	// ErrMFARequired роль сотрудника не позволяет отключить двухфакторную аутентификацию
+	ErrMFARequired = errors.New("two-factor authentication is required for the role")
+	// ErrMFAEnrollmentRequired перед вводом кода нужно подключить приложение-аутентификатор
+	ErrMFAEnrollmentRequired = errors.New("two-factor authentication enrollment is required")
+	ErrInvalidMFACode        = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAChallenge   = errors.New("invalid or expired mfa token")
+)
+
+// EmployeeTOTP секрет TOTP сотрудника. Пока ConfirmedAt пустой, подключение не завершено
+// и вход выполняется без второго фактора
+type EmployeeTOTP struct {
+	EmployeeId  int64      `db:"employee_id"`
+	Secret      string     `db:"secret"`
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+	// LastUsedStep последний принятый временной шаг: повторно тот же код не принимается
+	LastUsedStep int64     `db:"last_used_step"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// MFAChallenge незавершённый вход сотрудника, ожидающий ввода второго фактора
+type MFAChallenge struct {
+	Id         int64      `db:"id"`
+	EmployeeId int64      `db:"employee_id"`
+	TokenHash  string     `db:"token_hash"`
+	Attempts   int        `db:"attempts"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// MFAChallengeStats входы сотрудника со вторым фактором, начатые за период
+type MFAChallengeStats struct {
+	Count   int        `db:"count"`
+	FirstAt *time.Time `db:"first_at"`
+}
+
+// MFAChallengeResponse ответ на вход по паролю, если требуется второй фактор.
+// Token обменивается на пару JWT через POST /pub/auth/mfa/verify
+type MFAChallengeResponse struct {
+	MfaRequired        bool      `json:"mfa_required"`
+	Token              string    `json:"mfa_token"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	EnrollmentRequired bool      `json:"enrollment_required"`
+}
+
+// TOTPEnrollment данные для подключения приложения-аутентификатора. QRCode — PNG изображение URI
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode []byte `json:"qr_code"`
+}
+
+// MFAStatus состояние двухфакторной аутентификации сотрудника
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// MFAResult результат входа со вторым фактором. RecoveryCodes заполняется,
+// если при входе было завершено подключение TOTP
+type MFAResult struct {
+	JwtToken
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type MFARepository interface {
+	GetTOTP(ctx context.Context, employeeId int64, totp *EmployeeTOTP) error
+	// SavePendingTOTP сохраняет новый неподтверждённый секрет. Если TOTP уже подключен, возвращает ErrMFAAlreadyEnabled
+	SavePendingTOTP(ctx context.Context, employeeId int64, secret string) error
+	// ConfirmTOTP завершает подключение TOTP и заменяет коды восстановления
+	ConfirmTOTP(ctx context.Context, employeeId int64, step int64, recoveryCodeHashes []string) error
+	// UseTOTPStep принимает код шага step. Если шаг уже использован, возвращает ErrInvalidMFACode
+	UseTOTPStep(ctx context.Context, employeeId int64, step int64) error
+	// DeleteTOTP отключает TOTP и удаляет коды восстановления
+	DeleteTOTP(ctx context.Context, employeeId int64) error
+
+	ReplaceRecoveryCodes(ctx context.Context, employeeId int64, hashes []string) error
+	// UseRecoveryCode погашает код восстановления. Если код не найден или использован, возвращает ErrInvalidMFACode
+	UseRecoveryCode(ctx context.Context, employeeId int64, hash string) error
+	CountRecoveryCodes(ctx context.Context, employeeId int64) (int, error)
+
+	CreateChallenge(ctx context.Context, challenge *MFAChallenge) error
+	// ChallengeStats количество входов сотрудника, начатых после since
+	ChallengeStats(ctx context.Context, employeeId int64, since time.Time) (MFAChallengeStats, error)
+	// GetChallenge возвращает действующий вход по хешу токена или ErrInvalidMFAChallenge
+	GetChallenge(ctx context.Context, tokenHash string, challenge *MFAChallenge) error
+	// AttemptChallenge учитывает попытку ввода кода. Если попытки исчерпаны, возвращает ErrInvalidMFAChallenge
+	AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int, challenge *MFAChallenge) error
+	// CompleteChallenge помечает вход завершённым. Повторное завершение возвращает ErrInvalidMFAChallenge
+	CompleteChallenge(ctx context.Context, id int64) error
+}
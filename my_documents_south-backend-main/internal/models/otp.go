@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidOTP = errors.New("invalid or expired code")
+	// ErrOTPAttemptsExceeded исчерпаны попытки ввода кода, нужно запросить новый
+	ErrOTPAttemptsExceeded = errors.New("too many attempts, request a new code")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrInvalidPhone        = errors.New("invalid phone number")
+	ErrPhoneVerified       = errors.New("phone number is already verified")
+)
+
+// RateLimitError повторить запрос можно через RetryAfter
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many requests, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
+
+type OTPPurpose string
+
+const (
+	// OTPVerifyPhone подтверждение телефона клиента
+	OTPVerifyPhone OTPPurpose = "verify"
+	// OTPLogin вход клиента по SMS без пароля
+	OTPLogin OTPPurpose = "login"
+)
+
+// OTPCode одноразовый код, отправленный по SMS. Хранится только SHA-256 хеш кода
+type OTPCode struct {
+	Id        int64      `db:"id"`
+	Phone     string     `db:"phone"`
+	Purpose   OTPPurpose `db:"purpose"`
+	CodeHash  string     `db:"code_hash"`
+	Attempts  int        `db:"attempts"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// OTPStats отправки кодов на номер за период
+type OTPStats struct {
+	Count   int        `db:"count"`
+	FirstAt *time.Time `db:"first_at"`
+	LastAt  *time.Time `db:"last_at"`
+}
+
+type OTPRepository interface {
+	Create(ctx context.Context, code *OTPCode) error
+	Stats(ctx context.Context, phone string, purpose OTPPurpose, since time.Time) (OTPStats, error)
+	// Verify проверяет последний выданный код. Неверный код увеличивает счётчик попыток,
+	// верный помечается использованным. Возвращает ErrInvalidOTP или ErrOTPAttemptsExceeded
+	Verify(ctx context.Context, phone string, purpose OTPPurpose, codeHash string, maxAttempts int) error
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordReset токен восстановления пароля. Хранится только SHA-256 хеш токена
+type PasswordReset struct {
+	Id        int64      `db:"id"`
+	Subject   Actor      `db:"subject"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *PasswordReset) error
+	// CountSince количество токенов субъекта, выданных после since
+	CountSince(ctx context.Context, subject Actor, since time.Time) (int, error)
+	// Reset в одной транзакции помечает токен использованным, меняет пароль субъекта
+	// и отменяет остальные его токены. Если токен не найден, использован или истёк,
+	// возвращает ErrInvalidResetToken
+	Reset(ctx context.Context, tokenHash string, passwordHash string) (Actor, error)
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Permission право сотрудника на действие. Права выдаются роли, суперроль из setting имеет все права
+type Permission string
+
+const (
+	PermRoleRead              Permission = "role.read"
+	PermRoleWrite             Permission = "role.write"
+	PermTariffWrite           Permission = "tariff.write"
+	PermServiceWrite          Permission = "service.write"
+	PermEmployeeRead          Permission = "employee.read"
+	PermEmployeeWrite         Permission = "employee.write"
+	PermEmployeeInvite        Permission = "employee.invite"
+	PermUserReadAll           Permission = "user.read_all"
+	PermUserWrite             Permission = "user.write"
+	PermRequestCreate         Permission = "request.create"
+	PermRequestReadAll        Permission = "request.read_all"
+	PermRequestAssign         Permission = "request.assign"
+	PermRequestUpdateStatus   Permission = "request.update_status"
+	PermRequestUpdatePriority Permission = "request.update_priority"
+	PermRequestDelete         Permission = "request.delete"
+	PermSettingRead           Permission = "setting.read"
+	PermSettingWrite          Permission = "setting.write"
+)
+
+var ErrInvalidPermission = errors.New("invalid permission")
+
+// permissionDescriptions справочник всех известных прав
+var permissionDescriptions = map[Permission]string{
+	PermRoleRead:              "Просмотр ролей и их прав",
+	PermRoleWrite:             "Изменение и удаление ролей, назначение прав",
+	PermTariffWrite:           "Создание, изменение и удаление тарифов",
+	PermServiceWrite:          "Создание, изменение и удаление услуг",
+	PermEmployeeRead:          "Просмотр сотрудников",
+	PermEmployeeWrite:         "Изменение и удаление сотрудников, назначение услуг",
+	PermEmployeeInvite:        "Приглашение новых сотрудников",
+	PermUserReadAll:           "Просмотр всех клиентов",
+	PermUserWrite:             "Изменение и удаление клиентов",
+	PermRequestCreate:         "Создание заявок от имени клиента",
+	PermRequestReadAll:        "Просмотр всех заявок",
+	PermRequestAssign:         "Назначение сотрудника на заявку",
+	PermRequestUpdateStatus:   "Изменение статуса заявки",
+	PermRequestUpdatePriority: "Изменение приоритета заявки",
+	PermRequestDelete:         "Удаление заявок",
+	PermSettingRead:           "Просмотр настроек системы",
+	PermSettingWrite:          "Изменение настроек системы",
+}
+
+var AllPermissions = []Permission{
+	PermRoleRead,
+	PermRoleWrite,
+	PermTariffWrite,
+	PermServiceWrite,
+	PermEmployeeRead,
+	PermEmployeeWrite,
+	PermEmployeeInvite,
+	PermUserReadAll,
+	PermUserWrite,
+	PermRequestCreate,
+	PermRequestReadAll,
+	PermRequestAssign,
+	PermRequestUpdateStatus,
+	PermRequestUpdatePriority,
+	PermRequestDelete,
+	PermSettingRead,
+	PermSettingWrite,
+}
+
+func (p Permission) Valid() bool {
+	_, ok := permissionDescriptions[p]
+	return ok
+}
+
+type PermissionInfo struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+func NewPermissionInfos() []PermissionInfo {
+	infos := make([]PermissionInfo, 0, len(AllPermissions))
+	for _, permission := range AllPermissions {
+		infos = append(infos, PermissionInfo{Name: permission, Description: permissionDescriptions[permission]})
+	}
+	return infos
+}
+
+// RolePermissions права, выданные роли. Super означает суперроль, которой неявно доступны все права
+type RolePermissions struct {
+	RoleId      int          `json:"role_id"`
+	Super       bool         `json:"super"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Set возвращает набор прав с учётом суперроли
+func (p RolePermissions) Set() PermissionSet {
+	if p.Super {
+		return NewPermissionSet(AllPermissions...)
+	}
+	return NewPermissionSet(p.Permissions...)
+}
+
+type PermissionSet map[Permission]struct{}
+
+func NewPermissionSet(permissions ...Permission) PermissionSet {
+	set := make(PermissionSet, len(permissions))
+	for _, permission := range permissions {
+		set[permission] = struct{}{}
+	}
+	return set
+}
+
+func (s PermissionSet) Has(permission Permission) bool {
+	_, ok := s[permission]
+	return ok
+}
+
+// ValidatePermissions проверяет, что все права известны, и убирает повторы
+func ValidatePermissions(permissions []Permission) ([]Permission, error) {
+	seen := make(PermissionSet, len(permissions))
+	result := make([]Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !permission.Valid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPermission, permission)
+		}
+		if seen.Has(permission) {
+			continue
+		}
+		seen[permission] = struct{}{}
+		result = append(result, permission)
+	}
+	return result, nil
+}
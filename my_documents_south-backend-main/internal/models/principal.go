@@ -0,0 +1,34 @@
+package models
+
+// Principal аутентифицированный инициатор запроса, восстановленный из JWT.
+// SessionId — идентификатор сессии (семейства refresh токенов), к которой относится токен.
+// Permissions и Super заполняются middleware.Authorize, у клиентов набор пустой
+type Principal struct {
+	Type        ActorType
+	Id          int64
+	RoleId      *int
+	SessionId   string
+	Permissions PermissionSet
+	Super       bool
+}
+
+func (p *Principal) Actor() Actor {
+	return Actor{Type: p.Type, Id: p.Id}
+}
+
+func (p *Principal) IsEmployee() bool {
+	return p.Type == ActorEmployee
+}
+
+func (p *Principal) Can(permission Permission) bool {
+	return p.Permissions.Has(permission)
+}
+
+// CanAccessRequest клиент имеет доступ только к своим заявкам, сотрудник — к назначенным ему
+// или ко всем при наличии права request.read_all
+func (p *Principal) CanAccessRequest(req *Request) bool {
+	if !p.IsEmployee() {
+		return req.OwnerId == p.Id
+	}
+	return req.EmployeeId == p.Id || p.Can(PermRequestReadAll)
+}
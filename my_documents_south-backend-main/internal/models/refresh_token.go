@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused повторное использование refresh токена: вся цепочка токенов отзывается
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+)
+
+// RefreshToken выданный refresh токен. Id совпадает с claim jti, FamilyId — с claim sid:
+// все токены, полученные ротацией от одного входа, принадлежат одному семейству (сессии)
+type RefreshToken struct {
+	Id        string     `db:"id"`
+	FamilyId  string     `db:"family_id"`
+	Subject   Actor      `db:"subject"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// Active токен ещё не использован, не отозван и не истёк
+func (t *RefreshToken) Active(now time.Time) bool {
+	return t.RotatedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	GetById(ctx context.Context, id string, token *RefreshToken) error
+	// Rotate помечает токен id использованным и сохраняет next в одной транзакции.
+	// Если токен уже использован или отозван, возвращает ErrRefreshTokenReused
+	Rotate(ctx context.Context, id string, next *RefreshToken) error
+}
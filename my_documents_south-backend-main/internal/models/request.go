@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"my_documents_south_backend/internal/interfaces"
 	"time"
 )
@@ -19,23 +20,61 @@ type Request struct {
 	EmployeeId int64     `json:"employee_id,omitempty" db:"employee_id"`
 	Employee   *Employee `json:"employee" db:"employee"`
 
-	Priority  int16      `json:"priority,omitempty" db:"priority"`
-	Desc      string     `json:"desc,omitempty" db:"desc"`
-	Status    int16      `json:"status,omitempty" db:"status"`
+	// CreatedByEmployeeId сотрудник, создавший заявку от имени клиента
+	CreatedByEmployeeId *int64 `json:"created_by_employee_id,omitempty" db:"created_by_employee_id"`
+
+	Priority int16         `json:"priority,omitempty" db:"priority"`
+	Desc     string        `json:"desc,omitempty" db:"desc"`
+	Status   RequestStatus `json:"status,omitempty" db:"status"`
+	// Version увеличивается при каждом изменении заявки, отдаётся в ETag
+	Version   int32      `json:"version,omitempty" db:"version"`
 	CreatedAt time.Time  `json:"created_at,omitempty" db:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 	DesiredAt time.Time  `json:"desired_at,omitempty" db:"desired_at"`
 	ClosedAt  *time.Time `json:"closed_at,omitempty" db:"closed_at"`
 }
+
+var (
+	// ErrInvalidOwner владелец заявки должен быть существующим клиентом
+	ErrInvalidOwner    = errors.New("invalid owner_id: must reference an existing client")
+	ErrInvalidRequest  = errors.New("invalid request")
+	ErrRequestClosed   = errors.New("request is closed")
+	ErrRequestModified = errors.New("request was modified concurrently")
+	// ErrInvalidAssignee исполнителем заявки может быть только активный сотрудник
+	ErrInvalidAssignee = errors.New("invalid employee_id: must reference an active employee")
+)
+
+// RequestUpdate частичное изменение заявки, nil поля не меняются
+type RequestUpdate struct {
+	Name      *string    `json:"name"`
+	Desc      *string    `json:"desc"`
+	Priority  *int16     `json:"priority"`
+	ServiceId *int       `json:"service_id"`
+	DesiredAt *time.Time `json:"desired_at"`
+}
+
 type RequestRepository interface {
 	interfaces.EntityRepository[Request]
-	GetWithFilter(ctx context.Context, i *[]Request, filter Request) error
-	UpdateEmployee(ctx context.Context, id int64, employeeId int64) error
-	UpdateStatus(ctx context.Context, id int64, status int16) error
+	GetWithFilter(ctx context.Context, i *[]Request, filter RequestFilter) error
+	CountWithFilter(ctx context.Context, filter RequestFilter) (int, error)
+	// UpdateDetails сохраняет name, desc, priority, service_id и desired_at, если версия заявки
+	// равна req.Version, и записывает изменения в историю. Иначе возвращает ErrRequestModified
+	UpdateDetails(ctx context.Context, req *Request, actor Actor) error
+	UpdateEmployee(ctx context.Context, id int64, employeeId int64, actor Actor) error
+	UpdateStatus(ctx context.Context, id int64, from RequestStatus, to RequestStatus, actor Actor) error
+	UpdatePriority(ctx context.Context, id int64, priority int16, actor Actor) error
+	AddComment(ctx context.Context, id int64, comment string, actor Actor, event *RequestEvent) error
+	GetHistory(ctx context.Context, id int64, events *[]RequestEvent) error
 }
 type RequestService interface {
 	interfaces.EntityService[Request]
-	GetWithFilter(ctx context.Context, filter Request) ([]Request, error)
-	UpdateEmployee(ctx context.Context, id int64, employeeId int64) error
-	UpdateStatus(ctx context.Context, id int64, status int16) error
+	GetWithFilter(ctx context.Context, filter RequestFilter) (*RequestPage, error)
+	// Patch изменяет заявку, если её текущая версия равна version (0 — без проверки версии)
+	Patch(ctx context.Context, id int64, version int32, update RequestUpdate, actor Actor) (*Request, error)
+	UpdateEmployee(ctx context.Context, id int64, employeeId int64, actor Actor) error
+	UpdateStatus(ctx context.Context, id int64, status RequestStatus, actor Actor) error
+	UpdatePriority(ctx context.Context, id int64, priority int16, actor Actor) error
+	AddComment(ctx context.Context, id int64, comment string, actor Actor) (*RequestEvent, error)
+	GetHistory(ctx context.Context, id int64) ([]RequestEvent, error)
+	Transitions(ctx context.Context, id int64, actor Actor) ([]RequestStatus, error)
 }
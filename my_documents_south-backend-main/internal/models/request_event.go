@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidPriority = errors.New("invalid priority: must not be negative")
+	ErrInvalidComment  = errors.New("invalid comment: must contain from 1 to 4000 characters")
+)
+
+type RequestEventType string
+
+const (
+	EventStatusChanged    RequestEventType = "status_changed"
+	EventEmployeeAssigned RequestEventType = "employee_assigned"
+	EventPriorityChanged  RequestEventType = "priority_changed"
+	EventComment          RequestEventType = "comment"
+	// EventUpdated изменение name, desc, service_id или desired_at, в new_value — список полей
+	EventUpdated RequestEventType = "updated"
+)
+
+// RequestEvent запись в истории заявки: кто, когда и что изменил
+type RequestEvent struct {
+	Id        int64            `json:"id" db:"id"`
+	RequestId int64            `json:"request_id" db:"request_id"`
+	Type      RequestEventType `json:"type" db:"type"`
+	Actor     Actor            `json:"actor" db:"actor"`
+	OldValue  *string          `json:"old_value,omitempty" db:"old_value"`
+	NewValue  *string          `json:"new_value,omitempty" db:"new_value"`
+	Comment   *string          `json:"comment,omitempty" db:"comment"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRequestLimit = 50
+	MaxRequestLimit     = 200
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// RequestSortField поле, по которому разрешено сортировать список заявок
+type RequestSortField string
+
+const (
+	SortById        RequestSortField = "id"
+	SortByCreatedAt RequestSortField = "created_at"
+	SortByDesiredAt RequestSortField = "desired_at"
+	SortByPriority  RequestSortField = "priority"
+	SortByStatus    RequestSortField = "status"
+)
+
+var requestSortFields = map[RequestSortField]bool{
+	SortById:        true,
+	SortByCreatedAt: true,
+	SortByDesiredAt: true,
+	SortByPriority:  true,
+	SortByStatus:    true,
+}
+
+// RequestSort порядок списка заявок. При равных значениях поля заявки упорядочиваются по id в том же направлении
+type RequestSort struct {
+	Field RequestSortField
+	Desc  bool
+}
+
+// DefaultRequestSort сначала новые заявки
+var DefaultRequestSort = RequestSort{Field: SortByCreatedAt, Desc: true}
+
+// ParseRequestSort разбирает сортировку вида "priority" или "-created_at" (по убыванию)
+func ParseRequestSort(value string) (RequestSort, error) {
+	if value == "" {
+		return DefaultRequestSort, nil
+	}
+
+	sort := RequestSort{Field: RequestSortField(strings.TrimPrefix(value, "-")), Desc: strings.HasPrefix(value, "-")}
+	if !requestSortFields[sort.Field] {
+		return RequestSort{}, fmt.Errorf("%w: sort must be one of id, created_at, desired_at, priority, status", ErrInvalidFilter)
+	}
+	return sort, nil
+}
+
+func (s RequestSort) String() string {
+	if s.Desc {
+		return "-" + string(s.Field)
+	}
+	return string(s.Field)
+}
+
+// RequestCursor позиция последней заявки страницы для keyset пагинации.
+// Value — значение поля сортировки, Sort проверяет, что курсор получен с той же сортировкой
+type RequestCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    int64  `json:"id"`
+}
+
+// NewRequestCursor курсор, указывающий на заявку req при сортировке sort
+func NewRequestCursor(req *Request, sort RequestSort) *RequestCursor {
+	cursor := &RequestCursor{Sort: sort.String(), Id: req.Id}
+	switch sort.Field {
+	case SortByCreatedAt:
+		cursor.Value = req.CreatedAt.Format(time.RFC3339Nano)
+	case SortByDesiredAt:
+		cursor.Value = req.DesiredAt.Format(time.RFC3339Nano)
+	case SortByPriority:
+		cursor.Value = strconv.Itoa(int(req.Priority))
+	case SortByStatus:
+		cursor.Value = strconv.Itoa(int(req.Status))
+	}
+	return cursor
+}
+
+func (c *RequestCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeRequestCursor разбирает курсор из next_cursor и проверяет, что он выдан для сортировки sort
+func DecodeRequestCursor(value string, sort RequestSort) (*RequestCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor RequestCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id < 1 || cursor.Sort != sort.String() {
+		return nil, ErrInvalidCursor
+	}
+
+	switch sort.Field {
+	case SortByCreatedAt, SortByDesiredAt:
+		_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	case SortByPriority, SortByStatus:
+		_, err = strconv.ParseInt(cursor.Value, 10, 16)
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// RequestFilter условия выборки списка заявок. Пустые поля выборку не ограничивают,
+// значения внутри одного поля объединяются через ИЛИ, разные поля — через И
+type RequestFilter struct {
+	OwnerIds    []int64
+	ServiceIds  []int
+	EmployeeIds []int64
+	// Unassigned только заявки без исполнителя, несовместим с EmployeeIds
+	Unassigned  bool
+	Statuses    []RequestStatus
+	PriorityMin *int16
+	PriorityMax *int16
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	DesiredFrom *time.Time
+	DesiredTo   *time.Time
+
+	Sort   RequestSort
+	Limit  int
+	Cursor *RequestCursor
+}
+
+// Validate проверяет согласованность условий и подставляет сортировку и размер страницы по умолчанию
+func (f *RequestFilter) Validate() error {
+	if f.Unassigned && len(f.EmployeeIds) > 0 {
+		return fmt.Errorf("%w: unassigned conflicts with employee_id", ErrInvalidFilter)
+	}
+	if f.PriorityMin != nil && f.PriorityMax != nil && *f.PriorityMin > *f.PriorityMax {
+		return fmt.Errorf("%w: priority_min is greater than priority_max", ErrInvalidFilter)
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedFrom.After(*f.CreatedTo) {
+		return fmt.Errorf("%w: created_from is after created_to", ErrInvalidFilter)
+	}
+	if f.DesiredFrom != nil && f.DesiredTo != nil && f.DesiredFrom.After(*f.DesiredTo) {
+		return fmt.Errorf("%w: desired_from is after desired_to", ErrInvalidFilter)
+	}
+	for _, status := range f.Statuses {
+		if !status.Valid() {
+			return ErrInvalidStatus
+		}
+	}
+
+	if f.Sort.Field == "" {
+		f.Sort = DefaultRequestSort
+	}
+	if f.Limit < 1 {
+		f.Limit = DefaultRequestLimit
+	}
+	f.Limit = min(f.Limit, MaxRequestLimit)
+	return nil
+}
+
+// RequestPage страница списка заявок. NextCursor пуст на последней странице,
+// Total — количество заявок по фильтру без учёта пагинации, отдаётся в заголовке X-Total-Count
+type RequestPage struct {
+	Items      []Request `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Total      int       `json:"-"`
+}
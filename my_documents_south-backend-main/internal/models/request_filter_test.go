@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRequestSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RequestSort
+		wantErr bool
+	}{
+		{"", DefaultRequestSort, false},
+		{"priority", RequestSort{Field: SortByPriority}, false},
+		{"-created_at", RequestSort{Field: SortByCreatedAt, Desc: true}, false},
+		{"-id", RequestSort{Field: SortById, Desc: true}, false},
+		{"name", RequestSort{}, true},
+		{"--id", RequestSort{}, true},
+		{"+id", RequestSort{}, true},
+		{"-", RequestSort{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRequestSort(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRequestSort(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr && !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ParseRequestSort(%q) error = %v, want ErrInvalidFilter", tt.value, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseRequestSort(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+		if !tt.wantErr && tt.value != "" && got.String() != tt.value {
+			t.Errorf("ParseRequestSort(%q).String() = %q", tt.value, got.String())
+		}
+	}
+}
+
+func TestRequestCursorRoundTrip(t *testing.T) {
+	req := &Request{
+		Id:        17,
+		Priority:  3,
+		Status:    StatusInProgress,
+		CreatedAt: time.Date(2025, 3, 1, 10, 30, 0, 123456789, time.UTC),
+		DesiredAt: time.Date(2025, 4, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+	}
+
+	tests := []struct {
+		sort  RequestSort
+		value string
+	}{
+		{RequestSort{Field: SortById}, ""},
+		{RequestSort{Field: SortByCreatedAt, Desc: true}, "2025-03-01T10:30:00.123456789Z"},
+		{RequestSort{Field: SortByDesiredAt}, "2025-04-01T00:00:00+03:00"},
+		{RequestSort{Field: SortByPriority, Desc: true}, "3"},
+		{RequestSort{Field: SortByStatus}, "4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort.String(), func(t *testing.T) {
+			encoded := NewRequestCursor(req, tt.sort).Encode()
+			cursor, err := DecodeRequestCursor(encoded, tt.sort)
+			if err != nil {
+				t.Fatalf("DecodeRequestCursor() error = %v", err)
+			}
+			if cursor.Id != req.Id || cursor.Value != tt.value || cursor.Sort != tt.sort.String() {
+				t.Errorf("DecodeRequestCursor() = %+v, want id %d value %q", cursor, req.Id, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecodeRequestCursorInvalid(t *testing.T) {
+	sort := RequestSort{Field: SortByPriority}
+	raw := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+	valid := NewRequestCursor(&Request{Id: 5, Priority: 2}, sort).Encode()
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   RequestSort
+	}{
+		{"other direction", valid, RequestSort{Field: SortByPriority, Desc: true}},
+		{"other field", valid, RequestSort{Field: SortByStatus}},
+		{"not base64", "!!!", sort},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"priority","v":"2","id":5}`)), sort},
+		{"not json", raw("priority:2:5"), sort},
+		{"missing id", raw(`{"s":"priority","v":"2"}`), sort},
+		{"negative id", raw(`{"s":"priority","v":"2","id":-5}`), sort},
+		{"priority is not a number", raw(`{"s":"priority","v":"high","id":5}`), sort},
+		{"priority overflow", raw(`{"s":"priority","v":"40000","id":5}`), sort},
+		{"bad time", raw(`{"s":"-created_at","v":"yesterday","id":5}`), RequestSort{Field: SortByCreatedAt, Desc: true}},
+		{"empty", "", sort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeRequestCursor(tt.cursor, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeRequestCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestRequestFilterValidate(t *testing.T) {
+	low, high := int16(1), int16(5)
+	earlier, later := time.Now().Add(-time.Hour), time.Now()
+
+	tests := []struct {
+		name      string
+		filter    RequestFilter
+		wantErr   error
+		wantLimit int
+	}{
+		{"defaults", RequestFilter{}, nil, DefaultRequestLimit},
+		{"limit is kept", RequestFilter{Limit: 10}, nil, 10},
+		{"limit is clamped", RequestFilter{Limit: MaxRequestLimit + 1}, nil, MaxRequestLimit},
+		{"unassigned with employee", RequestFilter{Unassigned: true, EmployeeIds: []int64{1}}, ErrInvalidFilter, 0},
+		{"priority range", RequestFilter{PriorityMin: &low, PriorityMax: &high}, nil, DefaultRequestLimit},
+		{"inverted priority range", RequestFilter{PriorityMin: &high, PriorityMax: &low}, ErrInvalidFilter, 0},
+		{"inverted created range", RequestFilter{CreatedFrom: &later, CreatedTo: &earlier}, ErrInvalidFilter, 0},
+		{"inverted desired range", RequestFilter{DesiredFrom: &later, DesiredTo: &earlier}, ErrInvalidFilter, 0},
+		{"unknown status", RequestFilter{Statuses: []RequestStatus{StatusNew, 0}}, ErrInvalidStatus, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.filter.Limit != tt.wantLimit {
+				t.Errorf("Limit = %d, want %d", tt.filter.Limit, tt.wantLimit)
+			}
+			if tt.filter.Sort != DefaultRequestSort {
+				t.Errorf("Sort = %+v, want default %+v", tt.filter.Sort, DefaultRequestSort)
+			}
+		})
+	}
+}
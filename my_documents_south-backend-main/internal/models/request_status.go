@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type RequestStatus int16
+
+const (
+	StatusNew RequestStatus = iota + 1
+	StatusInReview
+	StatusAwaitingDocuments
+	StatusInProgress
+	StatusDone
+	StatusRejected
+	StatusCancelled
+)
+
+var ErrInvalidStatus = errors.New("invalid status")
+
+// ErrStatusChanged статус заявки был изменён другим запросом между чтением и записью
+var ErrStatusChanged = errors.New("request status was changed concurrently")
+
+var requestStatusNames = map[RequestStatus]string{
+	StatusNew:               "new",
+	StatusInReview:          "in_review",
+	StatusAwaitingDocuments: "awaiting_documents",
+	StatusInProgress:        "in_progress",
+	StatusDone:              "done",
+	StatusRejected:          "rejected",
+	StatusCancelled:         "cancelled",
+}
+
+// requestTransitions граф допустимых переходов между статусами заявки
+var requestTransitions = map[RequestStatus][]RequestStatus{
+	StatusNew:               {StatusInReview, StatusRejected, StatusCancelled},
+	StatusInReview:          {StatusAwaitingDocuments, StatusInProgress, StatusRejected, StatusCancelled},
+	StatusAwaitingDocuments: {StatusInReview, StatusInProgress, StatusRejected, StatusCancelled},
+	StatusInProgress:        {StatusAwaitingDocuments, StatusDone, StatusRejected, StatusCancelled},
+	StatusDone:              {},
+	StatusRejected:          {},
+	StatusCancelled:         {},
+}
+
+func (s RequestStatus) String() string {
+	if name, ok := requestStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int16(s))
+}
+
+// ParseRequestStatus разбирает статус по номеру или имени ("in_progress")
+func ParseRequestStatus(value string) (RequestStatus, error) {
+	if id, err := strconv.ParseInt(value, 10, 16); err == nil && RequestStatus(id).Valid() {
+		return RequestStatus(id), nil
+	}
+	for status, name := range requestStatusNames {
+		if name == value {
+			return status, nil
+		}
+	}
+	return 0, ErrInvalidStatus
+}
+
+func (s RequestStatus) Valid() bool {
+	_, ok := requestStatusNames[s]
+	return ok
+}
+
+// Terminal заявка в этом статусе считается закрытой
+func (s RequestStatus) Terminal() bool {
+	return s == StatusDone || s == StatusRejected || s == StatusCancelled
+}
+
+// Transitions возвращает статусы, в которые actor может перевести заявку.
+// Клиент может только отменить заявку, сотрудник может выполнить любой переход графа
+func (s RequestStatus) Transitions(actor Actor) []RequestStatus {
+	allowed := []RequestStatus{}
+	for _, next := range requestTransitions[s] {
+		if !actor.IsEmployee() && next != StatusCancelled {
+			continue
+		}
+		allowed = append(allowed, next)
+	}
+	return allowed
+}
+
+func (s RequestStatus) CanTransition(to RequestStatus, actor Actor) bool {
+	for _, next := range s.Transitions(actor) {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type StatusInfo struct {
+	Id   RequestStatus `json:"id"`
+	Name string        `json:"name"`
+}
+
+func NewStatusInfos(statuses []RequestStatus) []StatusInfo {
+	infos := make([]StatusInfo, 0, len(statuses))
+	for _, status := range statuses {
+		infos = append(infos, StatusInfo{Id: status, Name: status.String()})
+	}
+	return infos
+}
+
+// TransitionError переход From -> To не разрешён, Allowed содержит допустимые статусы
+type TransitionError struct {
+	From    RequestStatus
+	To      RequestStatus
+	Allowed []RequestStatus
+}
+
+func (e *TransitionError) Error() string {
+	names := make([]string, 0, len(e.Allowed))
+	for _, status := range e.Allowed {
+		names = append(names, status.String())
+	}
+	return fmt.Sprintf("transition from %s to %s is not allowed, allowed: [%s]", e.From, e.To, strings.Join(names, ", "))
+}
+
+type TransitionErrorResponse struct {
+	*ErrorResponse
+	Allowed []StatusInfo `json:"allowed"`
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+var (
+	testClient   = Actor{Type: ActorUser, Id: 1}
+	testEmployee = Actor{Type: ActorEmployee, Id: 1}
+)
+
+func TestRequestStatusTerminal(t *testing.T) {
+	tests := []struct {
+		status   RequestStatus
+		terminal bool
+	}{
+		{StatusNew, false},
+		{StatusInReview, false},
+		{StatusAwaitingDocuments, false},
+		{StatusInProgress, false},
+		{StatusDone, true},
+		{StatusRejected, true},
+		{StatusCancelled, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.Terminal(); got != tt.terminal {
+			t.Errorf("%s.Terminal() = %v, want %v", tt.status, got, tt.terminal)
+		}
+	}
+}
+
+func TestRequestStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		status RequestStatus
+		actor  Actor
+		want   []RequestStatus
+	}{
+		{"employee from new", StatusNew, testEmployee, []RequestStatus{StatusInReview, StatusRejected, StatusCancelled}},
+		{"employee from in_review", StatusInReview, testEmployee,
+			[]RequestStatus{StatusAwaitingDocuments, StatusInProgress, StatusRejected, StatusCancelled}},
+		{"employee from awaiting_documents", StatusAwaitingDocuments, testEmployee,
+			[]RequestStatus{StatusInReview, StatusInProgress, StatusRejected, StatusCancelled}},
+		{"employee from in_progress", StatusInProgress, testEmployee,
+			[]RequestStatus{StatusAwaitingDocuments, StatusDone, StatusRejected, StatusCancelled}},
+		{"client can only cancel", StatusInProgress, testClient, []RequestStatus{StatusCancelled}},
+		{"client from new", StatusNew, testClient, []RequestStatus{StatusCancelled}},
+		{"done is final", StatusDone, testEmployee, []RequestStatus{}},
+		{"rejected is final", StatusRejected, testEmployee, []RequestStatus{}},
+		{"cancelled is final for client", StatusCancelled, testClient, []RequestStatus{}},
+		{"unknown status", RequestStatus(42), testEmployee, []RequestStatus{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.status.Transitions(tt.actor)
+			if got == nil {
+				t.Fatal("Transitions() = nil, want empty slice for JSON []")
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Transitions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestStatusCanTransition(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  RequestStatus
+		to    RequestStatus
+		actor Actor
+		want  bool
+	}{
+		{"employee takes into review", StatusNew, StatusInReview, testEmployee, true},
+		{"employee cannot skip review", StatusNew, StatusInProgress, testEmployee, false},
+		{"employee cannot reopen", StatusDone, StatusInProgress, testEmployee, false},
+		{"employee cannot keep status", StatusInReview, StatusInReview, testEmployee, false},
+		{"client cancels", StatusInReview, StatusCancelled, testClient, true},
+		{"client cannot reject", StatusInReview, StatusRejected, testClient, false},
+		{"client cannot finish", StatusInProgress, StatusDone, testClient, false},
+		{"cancelled cannot be cancelled again", StatusCancelled, StatusCancelled, testClient, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.from.CanTransition(tt.to, tt.actor); got != tt.want {
+				t.Errorf("%s -> %s CanTransition() = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRequestStatus(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RequestStatus
+		wantErr bool
+	}{
+		{"1", StatusNew, false},
+		{"in_progress", StatusInProgress, false},
+		{"7", StatusCancelled, false},
+		{"0", 0, true},
+		{"8", 0, true},
+		{"IN_PROGRESS", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRequestStatus(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRequestStatus(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRequestStatus(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -7,18 +7,26 @@ import (
 )
 
 type Role struct {
-	Id        int        `json:"id,omitempty" db:"id"`
-	Name      string     `json:"name,omitempty" db:"name"`
-	CreatedAt *time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	Id   int    `json:"id,omitempty" db:"id"`
+	Name string `json:"name,omitempty" db:"name"`
+	// MfaRequired сотрудникам роли обязательна двухфакторная аутентификация
+	MfaRequired bool       `json:"mfa_required,omitempty" db:"mfa_required"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 }
 
 type RoleRepository interface {
 	interfaces.EntityRepository[Role]
-	SetSuperRole(c context.Context, id int) error
+	// SetSuperRoleIfUnset делает роль суперролью, если суперроль ещё не задана. Возвращает true, если роль назначена
+	SetSuperRoleIfUnset(c context.Context, id int) (bool, error)
 	GetSuperRole(c context.Context, role *Role) error
+	IsSuperRole(c context.Context, id int) (bool, error)
+	GetPermissions(c context.Context, id int, permissions *[]Permission) error
+	SetPermissions(c context.Context, id int, permissions []Permission) error
 }
 
 type RoleService interface {
 	interfaces.EntityService[Role]
+	GetPermissions(c context.Context, id int) (*RolePermissions, error)
+	SetPermissions(c context.Context, id int, permissions []Permission) (*RolePermissions, error)
 }
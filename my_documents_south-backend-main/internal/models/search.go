@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 50
+)
+
+// SnippetStart и SnippetStop ограничивают совпадения во фрагментах из хранилища. Управляющие символы
+// не встречаются в тексте, поэтому после экранирования фрагмента их можно безопасно заменить на теги
+const (
+	SnippetStart = "\x01"
+	SnippetStop  = "\x02"
+)
+
+var ErrInvalidSearchQuery = errors.New("invalid search query: must contain from 2 to 200 characters")
+
+type SearchHitType string
+
+const (
+	SearchHitRequest SearchHitType = "request"
+	SearchHitUser    SearchHitType = "user"
+	SearchHitMessage SearchHitType = "message"
+)
+
+// SearchHit найденная сущность. Snippet — фрагмент текста с совпадениями, выделенными тегом <mark>,
+// остальной текст экранирован. Для сообщений RequestId указывает заявку, к которой относится сообщение
+type SearchHit struct {
+	Type      SearchHitType `json:"type" db:"-"`
+	Id        int64         `json:"id" db:"id"`
+	RequestId *int64        `json:"request_id,omitempty" db:"request_id"`
+	Title     string        `json:"title" db:"title"`
+	Snippet   string        `json:"snippet" db:"snippet"`
+	Rank      float64       `json:"rank" db:"rank"`
+}
+
+// SearchResult результаты поиска, сгруппированные по типу сущности и упорядоченные по убыванию Rank
+type SearchResult struct {
+	Requests []SearchHit `json:"requests"`
+	Users    []SearchHit `json:"users"`
+	Messages []SearchHit `json:"messages"`
+}
+
+// SearchScope ограничения поиска по правам. EmployeeId ограничивает заявки и сообщения назначенными
+// сотруднику, Users разрешает поиск клиентов
+type SearchScope struct {
+	EmployeeId *int64
+	Users      bool
+}
+
+type SearchRepository interface {
+	SearchRequests(ctx context.Context, query string, scope SearchScope, limit int, hits *[]SearchHit) error
+	SearchMessages(ctx context.Context, query string, scope SearchScope, limit int, hits *[]SearchHit) error
+	SearchUsers(ctx context.Context, query string, pattern string, digits string, limit int, hits *[]SearchHit) error
+}
+
+type SearchService interface {
+	Search(ctx context.Context, principal *Principal, query string, limit int) (*SearchResult, error)
+}
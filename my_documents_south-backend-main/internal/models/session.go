@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session сессия входа клиента или сотрудника. Id совпадает с claim sid токенов
+// и с family_id refresh токенов, выданных в рамках сессии
+type Session struct {
+	Id         string     `json:"id" db:"id"`
+	Subject    Actor      `json:"subject" db:"subject"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	Ip         string     `json:"ip" db:"ip"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt time.Time  `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"-" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	Current    bool       `json:"current" db:"-"`
+}
+
+// SessionClient данные клиента, с которого выполняется вход или обновление токенов
+type SessionClient struct {
+	UserAgent string
+	Ip        string
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *Session) error
+	GetById(ctx context.Context, id string, session *Session) error
+	// GetActive возвращает не отозванные и не истёкшие сессии субъекта, последние использованные первыми
+	GetActive(ctx context.Context, subject Actor, sessions *[]Session) error
+	IsActive(ctx context.Context, id string) (bool, error)
+	// Touch отмечает использование сессии при обновлении токенов
+	Touch(ctx context.Context, id string, client SessionClient, expiresAt time.Time) error
+	// Revoke отзывает сессию и все её refresh токены
+	Revoke(ctx context.Context, id string) error
+	RevokeSubject(ctx context.Context, subject Actor) error
+	// RevokeOthers отзывает все сессии субъекта, кроме currentId
+	RevokeOthers(ctx context.Context, subject Actor, currentId string) error
+}
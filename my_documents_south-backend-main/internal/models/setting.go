@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidSettings = errors.New("invalid settings")
+	ErrSignupClosed    = errors.New("signup is closed")
+)
+
+// Settings настройки системы из единственной строки setting
+type Settings struct {
+	DefaultTariffId *int `json:"default_tariff_id" db:"default_tariff_id"`
+	SuperuserRoleId *int `json:"superuser_role_id" db:"superuser_role_id"`
+	// SignupOpen открыта ли регистрация клиентов
+	SignupOpen bool `json:"signup_open" db:"signup_open"`
+	// MaxUploadSize наибольший размер документа в байтах, не больше storage.max_upload_size
+	MaxUploadSize int64 `json:"max_upload_size" db:"max_upload_size"`
+	// SLADefaultPriority приоритет новой заявки, если он не указан
+	SLADefaultPriority int16 `json:"sla_default_priority" db:"sla_default_priority"`
+	// SLAResolutionHours срок выполнения новой заявки в часах, если desired_at не указан
+	SLAResolutionHours int        `json:"sla_resolution_hours" db:"sla_resolution_hours"`
+	SupportEmail       string     `json:"support_email" db:"support_email"`
+	SupportPhone       string     `json:"support_phone" db:"support_phone"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// PublicSettings настройки, которые доступны без авторизации
+type PublicSettings struct {
+	SignupOpen    bool   `json:"signup_open"`
+	MaxUploadSize int64  `json:"max_upload_size"`
+	SupportEmail  string `json:"support_email"`
+	SupportPhone  string `json:"support_phone"`
+}
+
+func (s *Settings) Public() PublicSettings {
+	return PublicSettings{
+		SignupOpen:    s.SignupOpen,
+		MaxUploadSize: s.MaxUploadSize,
+		SupportEmail:  s.SupportEmail,
+		SupportPhone:  s.SupportPhone,
+	}
+}
+
+type SettingRepository interface {
+	Get(ctx context.Context, settings *Settings) error
+	// Update перезаписывает все настройки и возвращает сохранённые значения в settings
+	Update(ctx context.Context, settings *Settings) error
+}
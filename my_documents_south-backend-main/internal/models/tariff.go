@@ -15,7 +15,8 @@ type Tariff struct {
 
 type TariffRepository interface {
 	interfaces.EntityRepository[Tariff]
-	SetDefault(c context.Context, id int) error
+	// SetDefaultIfUnset делает тариф тарифом по умолчанию, если он ещё не задан. Возвращает true, если тариф назначен
+	SetDefaultIfUnset(c context.Context, id int) (bool, error)
 	GetDefault(c context.Context, tariff *Tariff) error
 }
 
@@ -2,10 +2,17 @@ package models
 
 import (
 	"context"
+	"errors"
 	"my_documents_south_backend/internal/interfaces"
 	"time"
 )
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrInvalidUser  = errors.New("invalid user")
+	ErrUserExists   = errors.New("user with this email or phone already exists")
+)
+
 type User struct {
 	Id         int64  `json:"id,omitempty" db:"id"`
 	Name       string `json:"name,omitempty" db:"name"`
@@ -15,6 +22,9 @@ type User struct {
 	Phone      string `json:"phone,omitempty" db:"phone"`
 	Password   string `json:"password,omitempty" db:"password"`
 
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty" db:"phone_verified_at"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+
 	TariffId int     `json:"tariff_id,omitempty" db:"tariff_id"`
 	Tariff   *Tariff `json:"tariff,omitempty" db:"tariff"`
 
@@ -24,11 +34,31 @@ type User struct {
 	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 }
 
+// UserUpdate частичное изменение клиента, nil поля не меняются
+type UserUpdate struct {
+	Name       *string `json:"name"`
+	LastName   *string `json:"last_name"`
+	MiddleName *string `json:"middle_name"`
+	Email      *string `json:"email"`
+	Phone      *string `json:"phone"`
+	Inn        *string `json:"inn"`
+	Snils      *string `json:"snils"`
+}
+
+// UserRepository методы чтения не возвращают хеш пароля, кроме GetByPhone и GetPassword
 type UserRepository interface {
 	interfaces.EntityRepository[User]
 	GetByPhone(context.Context, string, *User) error
+	GetByIds(ctx context.Context, ids []int64, users *[]User) error
+	SetPhoneVerified(ctx context.Context, id int64) error
+	GetPassword(ctx context.Context, id int64) (string, error)
+	SetPassword(ctx context.Context, id int64, hash string) error
 }
 
 type UserService interface {
 	interfaces.EntityService[User]
+	// Patch меняет только переданные поля. Смена почты или телефона сбрасывает их подтверждение
+	Patch(ctx context.Context, id int64, update UserUpdate) (*User, error)
+	// ChangePassword меняет пароль клиента principal после проверки текущего и завершает его остальные сессии
+	ChangePassword(ctx context.Context, principal *Principal, current string, next string) error
 }
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+	"sync"
+	"time"
+)
+
+// sweepInterval как часто удаляются ключи без попыток и действующих блокировок
+const sweepInterval = time.Minute
+
+type loginAttempts struct {
+	failures []time.Time
+	lockout  *models.LoginLockout
+}
+
+// loginAttemptStore хранит попытки входа в памяти процесса.
+// При нескольких экземплярах приложения у каждого свои счётчики
+type loginAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*loginAttempts
+	window    time.Duration
+	retention time.Duration
+	sweptAt   time.Time
+}
+
+// NewLoginAttemptStore window окно подсчёта попыток, retention сколько хранить
+// истёкшую блокировку, чтобы следующая была длиннее
+func NewLoginAttemptStore(window time.Duration, retention time.Duration) models.LoginAttemptStore {
+	return &loginAttemptStore{
+		entries:   map[string]*loginAttempts{},
+		window:    window,
+		retention: retention,
+		sweptAt:   time.Now(),
+	}
+}
+
+func (s *loginAttemptStore) GetLockout(_ context.Context, key string, lockout *models.LoginLockout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && entry.lockout != nil {
+		*lockout = *entry.lockout
+	}
+	return nil
+}
+
+func (s *loginAttemptStore) AddFailure(_ context.Context, key string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &loginAttempts{}
+		s.entries[key] = entry
+	}
+	entry.failures = append(prune(entry.failures, since), now)
+	return len(entry.failures), nil
+}
+
+func (s *loginAttemptStore) Lock(_ context.Context, key string, until time.Time, level int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &loginAttempts{}
+		s.entries[key] = entry
+	}
+	entry.failures = nil
+	entry.lockout = &models.LoginLockout{Key: key, LockedUntil: until, Level: level, UpdatedAt: time.Now()}
+	return nil
+}
+
+func (s *loginAttemptStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *loginAttemptStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < sweepInterval {
+		return
+	}
+	s.sweptAt = now
+
+	for key, entry := range s.entries {
+		entry.failures = prune(entry.failures, now.Add(-s.window))
+		if len(entry.failures) == 0 && (entry.lockout == nil || entry.lockout.LockedUntil.Add(s.retention).Before(now)) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// prune удаляет попытки не позже since. Попытки хранятся по возрастанию времени
+func prune(failures []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(failures) && !failures[i].After(since) {
+		i++
+	}
+	return failures[i:]
+}
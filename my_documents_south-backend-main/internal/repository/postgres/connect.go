@@ -1,10 +1,11 @@
 package postgres
 
 import (
+	"log"
+	"my_documents_south_backend/internal/config"
+
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	"log"
-	"os"
 )
 
 func dieIf(err error) {
@@ -13,18 +14,13 @@ func dieIf(err error) {
 	}
 }
 
-func Connect() *sqlx.DB {
-	cfg := "host=localhost port=5433 user=mds_user password=zxcvbn dbname=mds sslmode=disable"
-	db, err := sqlx.Connect("pgx", cfg)
+func Connect(cfg config.Database) *sqlx.DB {
+	db, err := sqlx.Connect("pgx", cfg.DSN)
 	dieIf(err)
 
-	data, err := os.ReadFile("./schemas/v1/schema.sql")
-	dieIf(err)
-
-	schema := string(data)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	if _, err := db.Exec(schema); nil != err {
-		dieIf(err)
-	}
 	return db
 }
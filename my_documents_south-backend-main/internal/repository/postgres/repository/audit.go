@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type auditRepository struct {
+	conn *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) models.AuditRepository {
+	return &auditRepository{conn: db}
+}
+
+func (r *auditRepository) Create(c context.Context, event *models.AuditEvent) error {
+	query := `INSERT INTO "audit_log" (type, actor_type, actor_id, ip, details)
+			  VALUES ($1, $2, $3, $4, COALESCE($5::jsonb, '{}'))
+			  RETURNING id, created_at`
+
+	var actorType sql.NullString
+	var actorId sql.NullInt64
+	if event.Actor != nil {
+		actorType = sql.NullString{String: string(event.Actor.Type), Valid: true}
+		actorId = sql.NullInt64{Int64: event.Actor.Id, Valid: true}
+	}
+
+	var details sql.NullString
+	if len(event.Details) != 0 {
+		details = sql.NullString{String: string(event.Details), Valid: true}
+	}
+
+	return r.conn.QueryRowxContext(
+		c,
+		query,
+		event.Type,
+		actorType,
+		actorId,
+		sql.NullString{String: event.Ip, Valid: event.Ip != ""},
+		details,
+	).Scan(&event.Id, &event.CreatedAt)
+}
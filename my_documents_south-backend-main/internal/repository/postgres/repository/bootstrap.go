@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type bootstrapRepository struct {
+	conn *sqlx.DB
+}
+
+func NewBootstrapRepository(db *sqlx.DB) models.BootstrapRepository {
+	return &bootstrapRepository{conn: db}
+}
+
+func (r *bootstrapRepository) Run(c context.Context, bootstrap *models.Bootstrap, result *models.BootstrapResult) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		// блокировки не дают параллельным запускам создать двух первых сотрудников или разные суперроли
+		if _, err := tx.ExecContext(c, `LOCK TABLE "employee" IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		var employees bool
+		if err := tx.GetContext(c, &employees, `SELECT EXISTS(SELECT 1 FROM "employee")`); err != nil {
+			return err
+		}
+		if employees && bootstrap.Strict {
+			return models.ErrBootstrapClosed
+		}
+
+		var setting struct {
+			SuperRoleId     sql.NullInt64 `db:"superuser_role_id"`
+			DefaultTariffId sql.NullInt64 `db:"default_tariff_id"`
+		}
+		if err := tx.GetContext(c, &setting, `SELECT "superuser_role_id", "default_tariff_id" FROM "setting" FOR UPDATE`); err != nil {
+			return err
+		}
+
+		result.SuperRoleId = int(setting.SuperRoleId.Int64)
+		if !setting.SuperRoleId.Valid {
+			created, err := upsertByName(c, tx, "role", bootstrap.RoleName, &result.SuperRoleId)
+			if err != nil {
+				return err
+			}
+			result.RoleCreated = created
+
+			if _, err := tx.ExecContext(c, `UPDATE "setting" SET "superuser_role_id" = $1, "updated_at" = NOW()`, result.SuperRoleId); err != nil {
+				return err
+			}
+		}
+
+		result.DefaultTariffId = int(setting.DefaultTariffId.Int64)
+		if !setting.DefaultTariffId.Valid {
+			created, err := upsertByName(c, tx, "tariff", bootstrap.TariffName, &result.DefaultTariffId)
+			if err != nil {
+				return err
+			}
+			result.TariffCreated = created
+
+			if _, err := tx.ExecContext(c, `UPDATE "setting" SET "default_tariff_id" = $1, "updated_at" = NOW()`, result.DefaultTariffId); err != nil {
+				return err
+			}
+		}
+
+		if bootstrap.Admin == nil || employees {
+			return nil
+		}
+
+		admin := bootstrap.Admin
+		admin.RoleId = result.SuperRoleId
+		query := `INSERT INTO "employee" (name, last_name, middle_name, email, password, role_id, active)
+				  VALUES ($1, $2, $3, $4, $5, $6, TRUE)
+				  RETURNING *`
+		if err := tx.GetContext(
+			c,
+			admin,
+			query,
+			admin.Name,
+			admin.LastName,
+			admin.MiddleName,
+			admin.Email,
+			admin.Password,
+			admin.RoleId,
+		); err != nil {
+			return err
+		}
+
+		result.AdminId = admin.Id
+		result.AdminCreated = true
+		return nil
+	})
+}
+
+// upsertByName находит строку table по уникальному имени или создаёт её. Возвращает true, если строка создана
+func upsertByName(c context.Context, tx *sqlx.Tx, table string, name string, id *int) (bool, error) {
+	var row struct {
+		Id       int  `db:"id"`
+		Inserted bool `db:"inserted"`
+	}
+	// xmax = 0 только у строки, вставленной этим запросом
+	query := `INSERT INTO "` + table + `" (name) VALUES ($1)
+			  ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			  RETURNING id, (xmax = 0) AS inserted`
+	if err := tx.GetContext(c, &row, query, name); err != nil {
+		return false, err
+	}
+
+	*id = row.Id
+	return row.Inserted, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type documentRepository struct {
+	conn *sqlx.DB
+}
+
+func NewDocumentRepository(db *sqlx.DB) models.DocumentRepository {
+	return &documentRepository{conn: db}
+}
+
+const documentColumns = `
+	d.id,
+	d.request_id,
+	d.name,
+	d.mime_type,
+	d.size,
+	d.checksum,
+	d.storage_key,
+	d.uploaded_by_type AS "uploaded_by.type",
+	d.uploaded_by_id   AS "uploaded_by.id",
+	d.created_at
+`
+
+func (r *documentRepository) Create(c context.Context, document *models.Document) error {
+	query := `INSERT INTO "document" (request_id, name, mime_type, size, checksum, storage_key, uploaded_by_type, uploaded_by_id)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id, created_at`
+
+	return r.conn.QueryRowxContext(
+		c,
+		query,
+		document.RequestId,
+		document.Name,
+		document.MimeType,
+		document.Size,
+		document.Checksum,
+		document.StorageKey,
+		document.UploadedBy.Type,
+		document.UploadedBy.Id,
+	).Scan(&document.Id, &document.CreatedAt)
+}
+
+func (r *documentRepository) GetById(c context.Context, requestId int64, id int64, document *models.Document) error {
+	query := `SELECT` + documentColumns + `FROM "document" d WHERE d.request_id = $1 AND d.id = $2`
+	return r.conn.GetContext(c, document, query, requestId, id)
+}
+
+func (r *documentRepository) GetByRequest(c context.Context, requestId int64, documents *[]models.Document) error {
+	query := `SELECT` + documentColumns + `FROM "document" d WHERE d.request_id = $1 ORDER BY d.created_at, d.id`
+	return r.conn.SelectContext(c, documents, query, requestId)
+}
+
+func (r *documentRepository) Delete(c context.Context, requestId int64, id int64) error {
+	result, err := r.conn.ExecContext(c, `DELETE FROM "document" WHERE request_id = $1 AND id = $2`, requestId, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrDocumentNotFound
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type emailVerificationRepository struct {
+	conn *sqlx.DB
+}
+
+func NewEmailVerificationRepository(db *sqlx.DB) models.EmailVerificationRepository {
+	return &emailVerificationRepository{conn: db}
+}
+
+func (r *emailVerificationRepository) Create(c context.Context, verification *models.EmailVerification) error {
+	query := `INSERT INTO "email_verification" (subject_type, subject_id, email, token_hash, expires_at)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING id, created_at`
+
+	return r.conn.QueryRowxContext(
+		c,
+		query,
+		verification.Subject.Type,
+		verification.Subject.Id,
+		verification.Email,
+		verification.TokenHash,
+		verification.ExpiresAt,
+	).Scan(&verification.Id, &verification.CreatedAt)
+}
+
+func (r *emailVerificationRepository) CountSince(c context.Context, subject models.Actor, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM "email_verification" WHERE subject_type = $1 AND subject_id = $2 AND created_at > $3`
+	if err := r.conn.GetContext(c, &count, query, subject.Type, subject.Id, since); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *emailVerificationRepository) Verify(c context.Context, tokenHash string) (models.Actor, error) {
+	var verification models.EmailVerification
+
+	err := withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		// условие на used_at защищает от одновременного использования одного токена
+		err := tx.GetContext(c, &verification, `
+			UPDATE "email_verification"
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING subject_type AS "subject.type", subject_id AS "subject.id", email
+		`, tokenHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+
+		table, err := subjectTable(verification.Subject)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(c,
+			`UPDATE `+table+` SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1 AND email = $2`,
+			verification.Subject.Id,
+			verification.Email,
+		)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return models.ErrInvalidVerificationToken
+		}
+
+		_, err = tx.ExecContext(c, `
+			UPDATE "email_verification"
+			SET used_at = NOW()
+			WHERE subject_type = $1 AND subject_id = $2 AND used_at IS NULL
+		`, verification.Subject.Type, verification.Subject.Id)
+		return err
+	})
+	if err != nil {
+		return models.Actor{}, err
+	}
+	return verification.Subject, nil
+}
+
+func (r *emailVerificationRepository) IsVerified(c context.Context, subject models.Actor) (bool, error) {
+	table, err := subjectTable(subject)
+	if err != nil {
+		return false, err
+	}
+
+	var verified bool
+	query := `SELECT email_verified_at IS NOT NULL FROM ` + table + ` WHERE id = $1`
+	if err := r.conn.GetContext(c, &verified, query, subject.Id); err != nil {
+		return false, err
+	}
+	return verified, nil
+}
+
+// subjectTable таблица аккаунтов клиента или сотрудника
+func subjectTable(subject models.Actor) (string, error) {
+	switch subject.Type {
+	case models.ActorUser:
+		return `"user"`, nil
+	case models.ActorEmployee:
+		return `"employee"`, nil
+	default:
+		return "", fmt.Errorf("unknown subject type %q", subject.Type)
+	}
+}
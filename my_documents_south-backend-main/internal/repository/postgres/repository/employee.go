@@ -2,13 +2,21 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"my_documents_south_backend/internal/models"
+	"strconv"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// employeeColumns поля сотрудника без хеша пароля. middle_name и role_id в схеме допускают NULL
+const employeeColumns = `e.id, e.name, e.last_name, COALESCE(e.middle_name, '') AS middle_name, e.email,
+	e.email_verified_at, COALESCE(e.role_id, 0) AS role_id, e.active, e.created_at, e.updated_at`
+
 type employeeRepository struct {
 	conn *sqlx.DB
 }
@@ -29,6 +37,13 @@ func (r *employeeRepository) Create(c context.Context, employee *models.Employee
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
+	if err := checkEmployeeEmail(c, tx, employee.Email); err != nil {
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			return fmt.Errorf("failed to rollback transaction: %w", rollbackError)
+		}
+		return err
+	}
+
 	// Выполняем запрос на добавление сотрудника
 	if err := tx.GetContext(
 		c,
@@ -63,23 +78,97 @@ func (r *employeeRepository) Create(c context.Context, employee *models.Employee
 	return nil
 }
 
-func (r *employeeRepository) Get(c context.Context, employee *[]models.Employee) error { return nil }
+func (r *employeeRepository) Get(c context.Context, employees *[]models.Employee) error {
+	return r.conn.SelectContext(c, employees, `SELECT `+employeeColumns+` FROM "employee" e ORDER BY e.id`)
+}
 
 func (r *employeeRepository) GetById(c context.Context, id int, employee *models.Employee) error {
-	return nil
+	err := r.conn.GetContext(c, employee, `SELECT `+employeeColumns+` FROM "employee" e WHERE e.id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrEmployeeNotFound
+	}
+	return err
+}
+
+// GetByIds загружает сотрудников с указанными id одним запросом, отсутствующие id пропускаются
+func (r *employeeRepository) GetByIds(c context.Context, ids []int64, employees *[]models.Employee) error {
+	return r.conn.SelectContext(c, employees, `SELECT `+employeeColumns+` FROM "employee" e WHERE e.id = ANY($1)`, ids)
 }
 
 func (r *employeeRepository) GetByEmail(c context.Context, email string, employee *models.Employee) error {
-	err := r.conn.GetContext(c, employee, `SELECT * FROM "employee" WHERE "email" = $1`, email)
+	err := r.conn.GetContext(c, employee, `SELECT * FROM "employee" WHERE LOWER("email") = LOWER($1)`, email)
 	if err != nil {
-		return fmt.Errorf("not fount employee by %s", email)
+		return fmt.Errorf("employee not found by %s: %w", email, err)
 	}
 
 	return nil
 }
 
-// Update TODO
-func (r *employeeRepository) Update(c context.Context, employee *models.Employee) error { return nil }
+// Update сохраняет имя, почту и роль. При смене почты подтверждение сбрасывается
+func (r *employeeRepository) Update(c context.Context, employee *models.Employee) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		var taken bool
+		if err := tx.GetContext(c, &taken,
+			`SELECT EXISTS(SELECT 1 FROM "employee" WHERE LOWER(email) = LOWER($1) AND id <> $2)`,
+			employee.Email, employee.Id); err != nil {
+			return err
+		}
+		if taken {
+			return models.ErrEmployeeExists
+		}
+
+		query := `UPDATE "employee" e SET
+					name = $1,
+					last_name = $2,
+					middle_name = $3,
+					email = $4,
+					role_id = $5,
+					email_verified_at = CASE WHEN LOWER(e.email) = LOWER($4) THEN e.email_verified_at END,
+					updated_at = NOW()
+				  WHERE e.id = $6
+				  RETURNING ` + employeeColumns
+		err := tx.GetContext(
+			c,
+			employee,
+			query,
+			employee.Name,
+			employee.LastName,
+			employee.MiddleName,
+			employee.Email,
+			employee.RoleId,
+			employee.Id,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrEmployeeNotFound
+		}
+		return err
+	})
+}
+
+func (r *employeeRepository) GetPassword(c context.Context, id int64) (string, error) {
+	var hash string
+	err := r.conn.GetContext(c, &hash, `SELECT password FROM "employee" WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", models.ErrEmployeeNotFound
+	}
+	return hash, err
+}
+
+func (r *employeeRepository) SetPassword(c context.Context, id int64, hash string) error {
+	result, err := r.conn.ExecContext(c, `UPDATE "employee" SET password = $1, updated_at = NOW() WHERE id = $2`, hash, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrEmployeeNotFound)
+}
+
+func (r *employeeRepository) SetActive(c context.Context, id int64, active bool) error {
+	result, err := r.conn.ExecContext(c, `UPDATE "employee" SET active = $1, updated_at = NOW() WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrEmployeeNotFound)
+}
 
 func (r *employeeRepository) Delete(c context.Context, id int) error {
 	result, err := r.conn.ExecContext(c, `DELETE FROM "employee" WHERE id=$1`, id)
@@ -93,76 +182,143 @@ func (r *employeeRepository) Delete(c context.Context, id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("employee not found")
+		return models.ErrEmployeeNotFound
 	}
 	return nil
 }
 
+// AddService назначает услугу сотруднику. Возвращает models.ErrEmployeeNotFound для неизвестного сотрудника
+// и models.ErrInvalidEmployee для неизвестной услуги
 func (r *employeeRepository) AddService(c context.Context, employee_id int64, service_id int) error {
-	query := `INSERT INTO employee_specs (employee_id, service_id) VALUES ($1, $2) ON CONFLICT (employee_id, service_id) DO NOTHING;`
-	_, err := r.conn.ExecContext(c, query, employee_id, service_id)
-	return err
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if err := checkEmployeeExists(c, tx, employee_id); err != nil {
+			return err
+		}
+
+		var exists bool
+		if err := tx.GetContext(c, &exists, `SELECT EXISTS(SELECT 1 FROM "service" WHERE id = $1)`, service_id); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: service not found", models.ErrInvalidEmployee)
+		}
+
+		query := `INSERT INTO employee_specs (employee_id, service_id) VALUES ($1, $2) ON CONFLICT (employee_id, service_id) DO NOTHING;`
+		_, err := tx.ExecContext(c, query, employee_id, service_id)
+		return err
+	})
 }
 
+// RemoveService снимает услугу с сотрудника. Если услуга не назначена, возвращает models.ErrEmployeeServiceNotFound
 func (r *employeeRepository) RemoveService(c context.Context, employee_id int64, service_id int) error {
-	query := `DELETE FROM employee_specs WHERE employee_id = $1 AND service_id = $2;`
-	_, err := r.conn.ExecContext(c, query, employee_id, service_id)
-	return err
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if err := checkEmployeeExists(c, tx, employee_id); err != nil {
+			return err
+		}
+
+		query := `DELETE FROM employee_specs WHERE employee_id = $1 AND service_id = $2;`
+		result, err := tx.ExecContext(c, query, employee_id, service_id)
+		if err != nil {
+			return err
+		}
+		return requireAffected(result, models.ErrEmployeeServiceNotFound)
+	})
+}
+
+// checkEmployeeExists возвращает models.ErrEmployeeNotFound, если сотрудника нет
+func checkEmployeeExists(c context.Context, tx *sqlx.Tx, id int64) error {
+	var exists bool
+	if err := tx.GetContext(c, &exists, `SELECT EXISTS(SELECT 1 FROM "employee" WHERE id = $1)`, id); err != nil {
+		return err
+	}
+	if !exists {
+		return models.ErrEmployeeNotFound
+	}
+	return nil
+}
+
+// employeeWithServicesQuery сотрудники с ролью и услугами, собранными json_agg в одну колонку,
+// чтобы список загружался одним запросом
+const employeeWithServicesQuery = `SELECT
+				` + employeeColumns + `,
+				COALESCE(r.id, 0) AS "role.id",
+				COALESCE(r.name, '') AS "role.name",
+				COALESCE(srv.services, '[]') AS services_json
+			FROM "employee" e
+			LEFT JOIN "role" r ON e.role_id = r.id
+			LEFT JOIN LATERAL (
+				SELECT json_agg(json_build_object('id', s.id, 'name', s.name) ORDER BY s.id) AS services
+				FROM employee_specs es
+				INNER JOIN service s ON es.service_id = s.id
+				WHERE es.employee_id = e.id
+			) srv ON TRUE`
+
+type employeeWithServices struct {
+	models.Employee
+	ServicesJSON []byte `db:"services_json"`
+}
+
+func (e *employeeWithServices) decode() (models.Employee, error) {
+	employee := e.Employee
+	if err := json.Unmarshal(e.ServicesJSON, &employee.Services); err != nil {
+		return employee, fmt.Errorf("failed to decode employee services: %w", err)
+	}
+	return employee, nil
 }
 
 func (r *employeeRepository) GetByIdWithServices(ctx context.Context, id int64) (*models.Employee, error) {
-	employee := models.Employee{}
-	queryEmp := `SELECT * FROM employee WHERE id = $1`
-	if err := r.conn.GetContext(ctx, &employee, queryEmp, id); err != nil {
+	var row employeeWithServices
+	if err := r.conn.GetContext(ctx, &row, employeeWithServicesQuery+` WHERE e.id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrEmployeeNotFound
+		}
 		return nil, err
 	}
 
-	querySrv := `
-        SELECT s.id, s.name FROM service s
-        INNER JOIN employee_specs es ON es.service_id = s.id
-        WHERE es.employee_id = $1
-    `
-	services := []models.Service{}
-	if err := r.conn.SelectContext(ctx, &services, querySrv, id); err != nil {
+	employee, err := row.decode()
+	if err != nil {
 		return nil, err
 	}
-	employee.Services = services
-
 	return &employee, nil
 }
 
-func (r *employeeRepository) GetAllWithServices(ctx context.Context) ([]models.Employee, error) {
-	employees := []models.Employee{}
-	query := `SELECT
-				e.id,
-				e.name,
-				e.last_name,
-				e.middle_name,
-				e.email,
-				e.password,
-				e.active,
-				e.created_at,
-				e.updated_at,
-				r.id AS "role.id",
-				r.name AS "role.name"
-			FROM "employee" e
-			LEFT JOIN "role" r ON e.role_id = r.id`
+func (r *employeeRepository) GetAllWithServices(ctx context.Context, filter models.EmployeeFilter) ([]models.Employee, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.RoleId != nil {
+		args = append(args, *filter.RoleId)
+		conditions = append(conditions, "e.role_id = $"+strconv.Itoa(len(args)))
+	}
+	if filter.ServiceId != nil {
+		args = append(args, *filter.ServiceId)
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM employee_specs es WHERE es.employee_id = e.id AND es.service_id = $"+strconv.Itoa(len(args))+")")
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, "e.active = $"+strconv.Itoa(len(args)))
+	}
+
+	query := employeeWithServicesQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY e.id"
 
-	if err := r.conn.SelectContext(ctx, &employees, query); err != nil {
+	rows := []employeeWithServices{}
+	if err := r.conn.SelectContext(ctx, &rows, query, args...); err != nil {
 		return nil, err
 	}
 
-	for i := range employees {
-		querySrv := `
-            SELECT s.id, s.name FROM service s
-            INNER JOIN employee_specs es ON es.service_id = s.id
-            WHERE es.employee_id = $1
-        `
-		services := []models.Service{}
-		if err := r.conn.SelectContext(ctx, &services, querySrv, employees[i].Id); err != nil {
+	employees := make([]models.Employee, 0, len(rows))
+	for i := range rows {
+		employee, err := rows[i].decode()
+		if err != nil {
 			return nil, err
 		}
-		employees[i].Services = services
+		employees = append(employees, employee)
 	}
 
 	return employees, nil
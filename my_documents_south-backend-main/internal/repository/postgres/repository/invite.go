@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type inviteRepository struct {
+	conn *sqlx.DB
+}
+
+func NewInviteRepository(db *sqlx.DB) models.InviteRepository {
+	return &inviteRepository{conn: db}
+}
+
+// pendingInvite условие действующего приглашения
+const pendingInvite = `i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > NOW()`
+
+func (r *inviteRepository) Create(c context.Context, invite *models.EmployeeInvite) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if err := checkEmployeeEmail(c, tx, invite.Email); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(c, `
+			UPDATE "employee_invite" i SET revoked_at = NOW()
+			WHERE LOWER(i.email) = LOWER($1) AND `+pendingInvite, invite.Email,
+		); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO "employee_invite" (email, role_id, name, last_name, middle_name, token_hash, invited_by, expires_at)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				  RETURNING *`
+		return tx.GetContext(
+			c,
+			invite,
+			query,
+			invite.Email,
+			invite.RoleId,
+			invite.Name,
+			invite.LastName,
+			invite.MiddleName,
+			invite.TokenHash,
+			invite.InvitedBy,
+			invite.ExpiresAt,
+		)
+	})
+}
+
+func (r *inviteRepository) GetPending(c context.Context, invites *[]models.EmployeeInvite) error {
+	query := `SELECT i.*, r.name AS role_name
+			  FROM "employee_invite" i
+			  JOIN "role" r ON r.id = i.role_id
+			  WHERE ` + pendingInvite + `
+			  ORDER BY i.created_at DESC, i.id DESC`
+	return r.conn.SelectContext(c, invites, query)
+}
+
+func (r *inviteRepository) GetByToken(c context.Context, tokenHash string, invite *models.EmployeeInvite) error {
+	query := `SELECT i.*, r.name AS role_name
+			  FROM "employee_invite" i
+			  JOIN "role" r ON r.id = i.role_id
+			  WHERE i.token_hash = $1 AND ` + pendingInvite
+	err := r.conn.GetContext(c, invite, query, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrInvalidInviteToken
+	}
+	return err
+}
+
+func (r *inviteRepository) Revoke(c context.Context, id int64) error {
+	result, err := r.conn.ExecContext(c, `UPDATE "employee_invite" i SET revoked_at = NOW() WHERE i.id = $1 AND `+pendingInvite, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrInviteNotFound)
+}
+
+func (r *inviteRepository) Accept(c context.Context, tokenHash string, employee *models.Employee) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		var invite models.EmployeeInvite
+		err := tx.GetContext(c, &invite, `SELECT i.* FROM "employee_invite" i WHERE i.token_hash = $1 AND `+pendingInvite+` FOR UPDATE`, tokenHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrInvalidInviteToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := checkEmployeeEmail(c, tx, invite.Email); err != nil {
+			return err
+		}
+
+		employee.Email = invite.Email
+		employee.RoleId = invite.RoleId
+		query := `INSERT INTO "employee" (name, last_name, middle_name, email, password, role_id, active, email_verified_at)
+				  VALUES ($1, $2, $3, $4, $5, $6, TRUE, NOW())
+				  RETURNING *`
+		if err := tx.GetContext(
+			c,
+			employee,
+			query,
+			employee.Name,
+			employee.LastName,
+			employee.MiddleName,
+			employee.Email,
+			employee.Password,
+			employee.RoleId,
+		); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(c, `UPDATE "employee_invite" SET accepted_at = NOW(), employee_id = $1 WHERE id = $2`, employee.Id, invite.Id)
+		return err
+	})
+}
+
+// checkEmployeeEmail возвращает models.ErrEmployeeExists, если почта уже занята сотрудником
+func checkEmployeeEmail(c context.Context, tx *sqlx.Tx, email string) error {
+	var exists bool
+	if err := tx.GetContext(c, &exists, `SELECT EXISTS(SELECT 1 FROM "employee" WHERE LOWER(email) = LOWER($1))`, email); err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrEmployeeExists
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type loginAttemptRepository struct {
+	conn *sqlx.DB
+}
+
+// NewLoginAttemptRepository хранит попытки входа в Postgres, счётчики общие для всех экземпляров приложения
+func NewLoginAttemptRepository(db *sqlx.DB) models.LoginAttemptStore {
+	return &loginAttemptRepository{conn: db}
+}
+
+func (r *loginAttemptRepository) GetLockout(c context.Context, key string, lockout *models.LoginLockout) error {
+	err := r.conn.GetContext(c, lockout, `SELECT * FROM "login_lockout" WHERE key = $1`, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (r *loginAttemptRepository) AddFailure(c context.Context, key string, since time.Time) (int, error) {
+	var count int
+	err := withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		// попытки вне окна больше не нужны ни одному ключу
+		if _, err := tx.ExecContext(c, `DELETE FROM "login_attempt" WHERE created_at <= $1`, since); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(c, `INSERT INTO "login_attempt" (key) VALUES ($1)`, key); err != nil {
+			return err
+		}
+		return tx.GetContext(c, &count, `SELECT COUNT(*) FROM "login_attempt" WHERE key = $1`, key)
+	})
+	return count, err
+}
+
+func (r *loginAttemptRepository) Lock(c context.Context, key string, until time.Time, level int) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		query := `INSERT INTO "login_lockout" (key, locked_until, level)
+				  VALUES ($1, $2, $3)
+				  ON CONFLICT (key) DO UPDATE
+				  SET locked_until = EXCLUDED.locked_until, level = EXCLUDED.level, updated_at = NOW()`
+		if _, err := tx.ExecContext(c, query, key, until, level); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(c, `DELETE FROM "login_attempt" WHERE key = $1`, key)
+		return err
+	})
+}
+
+func (r *loginAttemptRepository) Reset(c context.Context, key string) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(c, `DELETE FROM "login_attempt" WHERE key = $1`, key); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(c, `DELETE FROM "login_lockout" WHERE key = $1`, key)
+		return err
+	})
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type messageRepository struct {
+	conn *sqlx.DB
+}
+
+func NewMessageRepository(db *sqlx.DB) models.MessageRepository {
+	return &messageRepository{conn: db}
+}
+
+func (r *messageRepository) Create(c context.Context, message *models.Message) error {
+	query := `INSERT INTO "message" (request_id, sender_type, sender_id, body)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+
+	return r.conn.QueryRowxContext(
+		c,
+		query,
+		message.RequestId,
+		message.Sender.Type,
+		message.Sender.Id,
+		message.Body,
+	).Scan(&message.Id, &message.CreatedAt)
+}
+
+// GetPage возвращает до limit сообщений с id меньше before, от новых к старым.
+// before = 0 означает начало с самого нового сообщения
+func (r *messageRepository) GetPage(c context.Context, requestId int64, before int64, limit int, messages *[]models.Message) error {
+	query := `
+		SELECT
+			m.id,
+			m.request_id,
+			m.sender_type AS "sender.type",
+			m.sender_id   AS "sender.id",
+			m.body,
+			m.created_at,
+			m.read_at
+		FROM "message" m
+		WHERE m.request_id = $1 AND ($2 = 0 OR m.id < $2)
+		ORDER BY m.id DESC
+		LIMIT $3
+	`
+	return r.conn.SelectContext(c, messages, query, requestId, before, limit)
+}
+
+// MarkRead отмечает прочитанными сообщения собеседника с id не больше upTo
+func (r *messageRepository) MarkRead(c context.Context, requestId int64, reader models.Actor, upTo int64) (int64, error) {
+	query := `
+		UPDATE "message"
+		SET read_at = NOW()
+		WHERE request_id = $1
+		  AND id <= $2
+		  AND read_at IS NULL
+		  AND NOT (sender_type = $3 AND sender_id = $4)
+	`
+	result, err := r.conn.ExecContext(c, query, requestId, upTo, reader.Type, reader.Id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *messageRepository) GetParticipants(c context.Context, requestId int64, participants *models.ChatParticipants) error {
+	return r.conn.GetContext(c, participants, `SELECT owner_id, employee_id FROM "request" WHERE id = $1`, requestId)
+}
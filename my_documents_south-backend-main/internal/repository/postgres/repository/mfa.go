@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type mfaRepository struct {
+	conn *sqlx.DB
+}
+
+func NewMFARepository(db *sqlx.DB) models.MFARepository {
+	return &mfaRepository{conn: db}
+}
+
+func (r *mfaRepository) GetTOTP(c context.Context, employeeId int64, totp *models.EmployeeTOTP) error {
+	return r.conn.GetContext(c, totp, `SELECT * FROM "employee_totp" WHERE employee_id = $1`, employeeId)
+}
+
+func (r *mfaRepository) SavePendingTOTP(c context.Context, employeeId int64, secret string) error {
+	query := `INSERT INTO "employee_totp" (employee_id, secret)
+			  VALUES ($1, $2)
+			  ON CONFLICT (employee_id) DO UPDATE
+			  SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+			  WHERE "employee_totp".confirmed_at IS NULL`
+
+	result, err := r.conn.ExecContext(c, query, employeeId, secret)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrMFAAlreadyEnabled)
+}
+
+func (r *mfaRepository) ConfirmTOTP(c context.Context, employeeId int64, step int64, recoveryCodeHashes []string) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(c, `
+			UPDATE "employee_totp"
+			SET confirmed_at = NOW(), last_used_step = $2
+			WHERE employee_id = $1 AND confirmed_at IS NULL AND last_used_step < $2
+		`, employeeId, step)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(result, models.ErrInvalidMFACode); err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(c, tx, employeeId, recoveryCodeHashes)
+	})
+}
+
+func (r *mfaRepository) UseTOTPStep(c context.Context, employeeId int64, step int64) error {
+	result, err := r.conn.ExecContext(c, `
+		UPDATE "employee_totp"
+		SET last_used_step = $2
+		WHERE employee_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+	`, employeeId, step)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrInvalidMFACode)
+}
+
+func (r *mfaRepository) DeleteTOTP(c context.Context, employeeId int64) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(c, `DELETE FROM "employee_recovery_code" WHERE employee_id = $1`, employeeId); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(c, `DELETE FROM "employee_totp" WHERE employee_id = $1`, employeeId)
+		return err
+	})
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(c context.Context, employeeId int64, hashes []string) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		return replaceRecoveryCodes(c, tx, employeeId, hashes)
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(c context.Context, employeeId int64, hash string) error {
+	result, err := r.conn.ExecContext(c, `
+		UPDATE "employee_recovery_code"
+		SET used_at = NOW()
+		WHERE employee_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, employeeId, hash)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrInvalidMFACode)
+}
+
+func (r *mfaRepository) CountRecoveryCodes(c context.Context, employeeId int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM "employee_recovery_code" WHERE employee_id = $1 AND used_at IS NULL`
+	if err := r.conn.GetContext(c, &count, query, employeeId); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *mfaRepository) CreateChallenge(c context.Context, challenge *models.MFAChallenge) error {
+	query := `INSERT INTO "mfa_challenge" (employee_id, token_hash, expires_at)
+			  VALUES ($1, $2, $3)
+			  RETURNING id, created_at`
+
+	return r.conn.QueryRowxContext(c, query, challenge.EmployeeId, challenge.TokenHash, challenge.ExpiresAt).
+		Scan(&challenge.Id, &challenge.CreatedAt)
+}
+
+func (r *mfaRepository) ChallengeStats(c context.Context, employeeId int64, since time.Time) (models.MFAChallengeStats, error) {
+	var stats models.MFAChallengeStats
+	query := `SELECT COUNT(*) AS count, MIN(created_at) AS first_at
+			  FROM "mfa_challenge"
+			  WHERE employee_id = $1 AND created_at > $2`
+	err := r.conn.GetContext(c, &stats, query, employeeId, since)
+	return stats, err
+}
+
+func (r *mfaRepository) GetChallenge(c context.Context, tokenHash string, challenge *models.MFAChallenge) error {
+	query := `SELECT * FROM "mfa_challenge" WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+	err := r.conn.GetContext(c, challenge, query, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrInvalidMFAChallenge
+	}
+	return err
+}
+
+func (r *mfaRepository) AttemptChallenge(c context.Context, tokenHash string, maxAttempts int, challenge *models.MFAChallenge) error {
+	// попытка учитывается до проверки кода, поэтому параллельные запросы не превысят лимит
+	query := `UPDATE "mfa_challenge"
+			  SET attempts = attempts + 1
+			  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+			  RETURNING *`
+
+	err := r.conn.GetContext(c, challenge, query, tokenHash, maxAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrInvalidMFAChallenge
+	}
+	return err
+}
+
+func (r *mfaRepository) CompleteChallenge(c context.Context, id int64) error {
+	result, err := r.conn.ExecContext(c, `UPDATE "mfa_challenge" SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrInvalidMFAChallenge)
+}
+
+func replaceRecoveryCodes(c context.Context, tx *sqlx.Tx, employeeId int64, hashes []string) error {
+	if _, err := tx.ExecContext(c, `DELETE FROM "employee_recovery_code" WHERE employee_id = $1`, employeeId); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(c,
+			`INSERT INTO "employee_recovery_code" (employee_id, code_hash) VALUES ($1, $2)`, employeeId, hash,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireAffected возвращает errNotAffected, если запрос не изменил ни одной строки
+func requireAffected(result sql.Result, errNotAffected error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errNotAffected
+	}
+	return nil
+}
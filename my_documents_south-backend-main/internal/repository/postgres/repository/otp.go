@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type otpRepository struct {
+	conn *sqlx.DB
+}
+
+func NewOTPRepository(db *sqlx.DB) models.OTPRepository {
+	return &otpRepository{conn: db}
+}
+
+func (r *otpRepository) Create(c context.Context, code *models.OTPCode) error {
+	query := `INSERT INTO "otp_code" (phone, purpose, code_hash, expires_at)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+
+	return r.conn.QueryRowxContext(c, query, code.Phone, code.Purpose, code.CodeHash, code.ExpiresAt).
+		Scan(&code.Id, &code.CreatedAt)
+}
+
+func (r *otpRepository) Stats(c context.Context, phone string, purpose models.OTPPurpose, since time.Time) (models.OTPStats, error) {
+	var stats models.OTPStats
+	query := `SELECT COUNT(*) AS count, MIN(created_at) AS first_at, MAX(created_at) AS last_at
+			  FROM "otp_code"
+			  WHERE phone = $1 AND purpose = $2 AND created_at > $3`
+	err := r.conn.GetContext(c, &stats, query, phone, purpose, since)
+	return stats, err
+}
+
+func (r *otpRepository) Verify(c context.Context, phone string, purpose models.OTPPurpose, codeHash string, maxAttempts int) error {
+	// результат проверки возвращается отдельно, чтобы неудачная попытка тоже сохранилась
+	var result error
+
+	err := withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		var code models.OTPCode
+		err := tx.GetContext(c, &code, `
+			SELECT id, code_hash, attempts, expires_at, used_at
+			FROM "otp_code"
+			WHERE phone = $1 AND purpose = $2
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+			FOR UPDATE
+		`, phone, purpose)
+		if errors.Is(err, sql.ErrNoRows) {
+			result = models.ErrInvalidOTP
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case code.UsedAt != nil || !time.Now().Before(code.ExpiresAt):
+			result = models.ErrInvalidOTP
+			return nil
+		case code.Attempts >= maxAttempts:
+			result = models.ErrOTPAttemptsExceeded
+			return nil
+		}
+
+		if subtle.ConstantTimeCompare([]byte(code.CodeHash), []byte(codeHash)) != 1 {
+			result = models.ErrInvalidOTP
+			if code.Attempts+1 >= maxAttempts {
+				result = models.ErrOTPAttemptsExceeded
+			}
+			_, err := tx.ExecContext(c, `UPDATE "otp_code" SET attempts = attempts + 1 WHERE id = $1`, code.Id)
+			return err
+		}
+
+		_, err = tx.ExecContext(c, `UPDATE "otp_code" SET used_at = NOW() WHERE id = $1`, code.Id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type passwordResetRepository struct {
+	conn *sqlx.DB
+}
+
+func NewPasswordResetRepository(db *sqlx.DB) models.PasswordResetRepository {
+	return &passwordResetRepository{conn: db}
+}
+
+func (r *passwordResetRepository) Create(c context.Context, reset *models.PasswordReset) error {
+	query := `INSERT INTO "password_reset" (subject_type, subject_id, token_hash, expires_at)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+
+	return r.conn.QueryRowxContext(
+		c,
+		query,
+		reset.Subject.Type,
+		reset.Subject.Id,
+		reset.TokenHash,
+		reset.ExpiresAt,
+	).Scan(&reset.Id, &reset.CreatedAt)
+}
+
+func (r *passwordResetRepository) CountSince(c context.Context, subject models.Actor, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM "password_reset" WHERE subject_type = $1 AND subject_id = $2 AND created_at > $3`
+	if err := r.conn.GetContext(c, &count, query, subject.Type, subject.Id, since); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *passwordResetRepository) Reset(c context.Context, tokenHash string, passwordHash string) (models.Actor, error) {
+	var subject models.Actor
+
+	err := withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		// условие на used_at защищает от одновременного использования одного токена
+		err := tx.GetContext(c, &subject, `
+			UPDATE "password_reset"
+			SET used_at = NOW()
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING subject_type AS "type", subject_id AS "id"
+		`, tokenHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		table, err := subjectTable(subject)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(c, `UPDATE `+table+` SET password = $1, updated_at = NOW() WHERE id = $2`, passwordHash, subject.Id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return models.ErrInvalidResetToken
+		}
+
+		_, err = tx.ExecContext(c, `
+			UPDATE "password_reset"
+			SET used_at = NOW()
+			WHERE subject_type = $1 AND subject_id = $2 AND used_at IS NULL
+		`, subject.Type, subject.Id)
+		return err
+	})
+	if err != nil {
+		return models.Actor{}, err
+	}
+	return subject, nil
+}
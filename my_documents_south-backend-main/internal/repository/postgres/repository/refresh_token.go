@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type refreshTokenRepository struct {
+	conn *sqlx.DB
+}
+
+func NewRefreshTokenRepository(db *sqlx.DB) models.RefreshTokenRepository {
+	return &refreshTokenRepository{conn: db}
+}
+
+func (r *refreshTokenRepository) Create(c context.Context, token *models.RefreshToken) error {
+	return insertRefreshToken(c, r.conn, token)
+}
+
+func (r *refreshTokenRepository) GetById(c context.Context, id string, token *models.RefreshToken) error {
+	query := `
+		SELECT
+			t.id,
+			t.family_id,
+			t.subject_type AS "subject.type",
+			t.subject_id   AS "subject.id",
+			t.expires_at,
+			t.rotated_at,
+			t.revoked_at,
+			t.created_at
+		FROM "refresh_token" t
+		WHERE t.id = $1
+	`
+	return r.conn.GetContext(c, token, query, id)
+}
+
+func (r *refreshTokenRepository) Rotate(c context.Context, id string, next *models.RefreshToken) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		// условие на rotated_at защищает от одновременной ротации одного токена
+		result, err := tx.ExecContext(c, `
+			UPDATE "refresh_token"
+			SET rotated_at = NOW()
+			WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+		`, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return models.ErrRefreshTokenReused
+		}
+
+		return insertRefreshToken(c, tx, next)
+	})
+}
+
+func insertRefreshToken(c context.Context, conn sqlx.QueryerContext, token *models.RefreshToken) error {
+	query := `INSERT INTO "refresh_token" (id, family_id, subject_type, subject_id, expires_at)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING created_at`
+
+	return conn.QueryRowxContext(
+		c,
+		query,
+		token.Id,
+		token.FamilyId,
+		token.Subject.Type,
+		token.Subject.Id,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+}
@@ -2,13 +2,22 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"my_documents_south_backend/internal/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// requestColumns поля заявки с алиасом r, NULL в service_id и employee_id возвращается как 0
+const requestColumns = `r.id, r.name, COALESCE(r.service_id, 0) AS service_id, r.owner_id,
+	COALESCE(r.employee_id, 0) AS employee_id, r.created_by_employee_id, r.priority, r."desc",
+	r.status, r.version, r.desired_at, r.created_at, r.updated_at, r.closed_at`
+
 type requestRepository struct {
 	conn *sqlx.DB
 }
@@ -18,9 +27,10 @@ func NewRequestRepository(db *sqlx.DB) models.RequestRepository {
 }
 
 func (r *requestRepository) Create(c context.Context, req *models.Request) error {
-	query := `INSERT INTO "request" (name, service_id, owner_id, employee_id, priority, "desc", status, desired_at)
-        	  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-        	  RETURNING *`
+	// service_id и employee_id необязательны, 0 сохраняется как NULL
+	query := `INSERT INTO "request" AS r (name, service_id, owner_id, employee_id, created_by_employee_id, priority, "desc", status, desired_at)
+        	  VALUES ($1, NULLIF($2, 0), $3, NULLIF($4, 0), $5, $6, $7, $8, $9)
+        	  RETURNING ` + requestColumns
 
 	// Начинаем транзакцию
 	tx, err := r.conn.Beginx()
@@ -36,6 +46,7 @@ func (r *requestRepository) Create(c context.Context, req *models.Request) error
 		req.ServiceId,
 		req.OwnerId,
 		req.EmployeeId,
+		req.CreatedByEmployeeId,
 		req.Priority,
 		req.Desc,
 		req.Status,
@@ -67,17 +78,9 @@ func (r *requestRepository) Get(c context.Context, req *[]models.Request) error
 
 func (r *requestRepository) GetById(c context.Context, id int, req *models.Request) error {
 	query := `
-		SELECT 
-			r.id,
-			r.owner_id,
-			r.employee_id,
-			r.status,
-			r.desc,
-			r.desired_at,
-			r.created_at,
-			r.updated_at,
-			s.id   AS "service.id",
-			s.name AS "service.name"
+		SELECT ` + requestColumns + `,
+			COALESCE(s.id, 0)    AS "service.id",
+			COALESCE(s.name, '') AS "service.name"
 		FROM "request" r
 		LEFT JOIN "service" s ON r.service_id = s.id
 		WHERE r.id=$1
@@ -90,73 +93,367 @@ func (r *requestRepository) GetById(c context.Context, id int, req *models.Reque
 	return nil
 }
 
-func (r *requestRepository) GetWithFilter(ctx context.Context, req *[]models.Request, filter models.Request) error {
-	query := `
-		SELECT 
-			r.id,
-			r.owner_id,
-			r.employee_id,
-			r.status,
-			r.desc,
-			r.desired_at,
-			r.created_at,
-			r.updated_at,
-			s.id   AS "service.id",
-			s.name AS "service.name"
-		FROM "request" r
-		LEFT JOIN "service" s ON r.service_id = s.id
-		WHERE 1=1
-	`
+// requestSortColumns колонки и типы полей сортировки для условия курсора
+var requestSortColumns = map[models.RequestSortField][2]string{
+	models.SortById:        {"r.id", "bigint"},
+	models.SortByCreatedAt: {"r.created_at", "timestamptz"},
+	models.SortByDesiredAt: {"r.desired_at", "timestamptz"},
+	models.SortByPriority:  {"r.priority", "smallint"},
+	models.SortByStatus:    {"r.status", "smallint"},
+}
 
+// requestFilterWhere строит условие WHERE по фильтру без учёта курсора
+func requestFilterWhere(filter models.RequestFilter) (string, []interface{}) {
+	where := []string{"TRUE"}
 	args := []interface{}{}
-	i := 1
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
 
-	if filter.OwnerId != 0 {
-		query += fmt.Sprintf(" AND owner_id = $%d", i)
-		args = append(args, filter.OwnerId)
-		i++
+	if len(filter.OwnerIds) > 0 {
+		add("r.owner_id = ANY($%d)", filter.OwnerIds)
+	}
+	if len(filter.ServiceIds) > 0 {
+		ids := make([]int64, 0, len(filter.ServiceIds))
+		for _, id := range filter.ServiceIds {
+			ids = append(ids, int64(id))
+		}
+		add("r.service_id = ANY($%d)", ids)
 	}
-	if filter.ServiceId != 0 {
-		query += fmt.Sprintf(" AND service_id = $%d", i)
-		args = append(args, filter.ServiceId)
-		i++
+	if len(filter.EmployeeIds) > 0 {
+		add("r.employee_id = ANY($%d)", filter.EmployeeIds)
+	}
+	if filter.Unassigned {
+		where = append(where, "r.employee_id IS NULL")
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]int16, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, int16(status))
+		}
+		add("r.status = ANY($%d)", statuses)
 	}
-	if !filter.DesiredAt.IsZero() {
-		query += fmt.Sprintf(" AND desired_at <= $%d", i)
-		args = append(args, filter.DesiredAt)
-		i++
+	if filter.PriorityMin != nil {
+		add("r.priority >= $%d", *filter.PriorityMin)
 	}
-	if filter.Status != 0 {
-		query += fmt.Sprintf(" AND status = $%d", i)
-		args = append(args, filter.Status)
-		i++
+	if filter.PriorityMax != nil {
+		add("r.priority <= $%d", *filter.PriorityMax)
 	}
-	if filter.EmployeeId != 0 {
-		query += fmt.Sprintf(" AND employee_id = $%d", i)
-		args = append(args, filter.EmployeeId)
-		i++
+	if filter.CreatedFrom != nil {
+		add("r.created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		add("r.created_at <= $%d", *filter.CreatedTo)
+	}
+	if filter.DesiredFrom != nil {
+		add("r.desired_at >= $%d", *filter.DesiredFrom)
+	}
+	if filter.DesiredTo != nil {
+		add("r.desired_at <= $%d", *filter.DesiredTo)
 	}
 
-	err := r.conn.SelectContext(ctx, req, query, args...)
-	if err != nil {
-		return err
+	return strings.Join(where, " AND "), args
+}
+
+// GetWithFilter выбирает не более filter.Limit заявок после filter.Cursor в порядке filter.Sort
+func (r *requestRepository) GetWithFilter(ctx context.Context, req *[]models.Request, filter models.RequestFilter) error {
+	where, args := requestFilterWhere(filter)
+
+	column := requestSortColumns[filter.Sort.Field]
+	direction, compare := "ASC", ">"
+	if filter.Sort.Desc {
+		direction, compare = "DESC", "<"
 	}
 
-	return nil
+	if filter.Cursor != nil {
+		if filter.Sort.Field == models.SortById {
+			args = append(args, filter.Cursor.Id)
+			where += fmt.Sprintf(" AND r.id %s $%d", compare, len(args))
+		} else {
+			value, err := requestCursorValue(filter.Sort.Field, filter.Cursor.Value)
+			if err != nil {
+				return models.ErrInvalidCursor
+			}
+			args = append(args, value, filter.Cursor.Id)
+			where += fmt.Sprintf(" AND (%s, r.id) %s ($%d::%s, $%d)", column[0], compare, len(args)-1, column[1], len(args))
+		}
+	}
+
+	order := fmt.Sprintf("%s %s, r.id %s", column[0], direction, direction)
+	if filter.Sort.Field == models.SortById {
+		order = "r.id " + direction
+	}
+
+	args = append(args, filter.Limit)
+	query := `
+		SELECT ` + requestColumns + `,
+			COALESCE(s.id, 0)    AS "service.id",
+			COALESCE(s.name, '') AS "service.name"
+		FROM "request" r
+		LEFT JOIN "service" s ON r.service_id = s.id
+		WHERE ` + where + `
+		ORDER BY ` + order + fmt.Sprintf(`
+		LIMIT $%d`, len(args))
+
+	return r.conn.SelectContext(ctx, req, query, args...)
+}
+
+// requestCursorValue приводит значение поля сортировки из курсора к типу колонки
+func requestCursorValue(field models.RequestSortField, value string) (interface{}, error) {
+	switch field {
+	case models.SortByCreatedAt, models.SortByDesiredAt:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		v, err := strconv.ParseInt(value, 10, 16)
+		return int16(v), err
+	}
 }
 
+// CountWithFilter количество заявок по фильтру без учёта курсора и лимита
+func (r *requestRepository) CountWithFilter(ctx context.Context, filter models.RequestFilter) (int, error) {
+	where, args := requestFilterWhere(filter)
+
+	var total int
+	err := r.conn.GetContext(ctx, &total, `SELECT COUNT(*) FROM "request" r WHERE `+where, args...)
+	return total, err
+}
+
+// Update не используется: изменение заявки с проверкой версии и записью истории выполняет UpdateDetails
 func (r *requestRepository) Update(c context.Context, req *models.Request) error { return nil }
 
-func (r *requestRepository) UpdateEmployee(ctx context.Context, id int64, employee_id int64) error {
-	query := `UPDATE request SET employee_id = $1 WHERE id = $2`
-	_, err := r.conn.ExecContext(ctx, query, employee_id, id)
-	return err
+func (r *requestRepository) UpdateDetails(ctx context.Context, req *models.Request, actor models.Actor) error {
+	return withTx(ctx, r.conn, func(tx *sqlx.Tx) error {
+		var previous models.Request
+		query := `SELECT ` + requestColumns + ` FROM "request" r WHERE r.id = $1 FOR UPDATE`
+		if err := tx.GetContext(ctx, &previous, query, req.Id); err != nil {
+			return err
+		}
+		if previous.Version != req.Version {
+			return models.ErrRequestModified
+		}
+		if req.ServiceId != 0 && req.ServiceId != previous.ServiceId {
+			var exists bool
+			if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM "service" WHERE id = $1)`, req.ServiceId); err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("%w: service not found", models.ErrInvalidRequest)
+			}
+		}
+
+		query = `UPDATE "request" r
+				 SET name = $1,
+				     "desc" = $2,
+				     priority = $3,
+				     service_id = NULLIF($4, 0),
+				     desired_at = $5,
+				     version = r.version + 1,
+				     updated_at = NOW()
+				 WHERE r.id = $6
+				 RETURNING ` + requestColumns
+		if err := tx.GetContext(ctx, req, query, req.Name, req.Desc, req.Priority, req.ServiceId, req.DesiredAt, req.Id); err != nil {
+			return err
+		}
+
+		if previous.Priority != req.Priority {
+			err := insertRequestEvent(ctx, tx, &models.RequestEvent{
+				RequestId: req.Id,
+				Type:      models.EventPriorityChanged,
+				Actor:     actor,
+				OldValue:  eventValue(strconv.Itoa(int(previous.Priority))),
+				NewValue:  eventValue(strconv.Itoa(int(req.Priority))),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var fields []string
+		if previous.Name != req.Name {
+			fields = append(fields, "name")
+		}
+		if previous.Desc != req.Desc {
+			fields = append(fields, "desc")
+		}
+		if previous.ServiceId != req.ServiceId {
+			fields = append(fields, "service_id")
+		}
+		if !previous.DesiredAt.Equal(req.DesiredAt) {
+			fields = append(fields, "desired_at")
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+
+		return insertRequestEvent(ctx, tx, &models.RequestEvent{
+			RequestId: req.Id,
+			Type:      models.EventUpdated,
+			Actor:     actor,
+			NewValue:  eventValue(strings.Join(fields, ",")),
+		})
+	})
+}
+
+// UpdateEmployee назначает исполнителя заявки. Если заявка закрыта, возвращает models.ErrRequestClosed
+func (r *requestRepository) UpdateEmployee(ctx context.Context, id int64, employee_id int64, actor models.Actor) error {
+	return withTx(ctx, r.conn, func(tx *sqlx.Tx) error {
+		var current struct {
+			EmployeeId sql.NullInt64        `db:"employee_id"`
+			Status     models.RequestStatus `db:"status"`
+		}
+		if err := tx.GetContext(ctx, &current, `SELECT employee_id, status FROM "request" WHERE id = $1 FOR UPDATE`, id); err != nil {
+			return err
+		}
+		// статус мог измениться после проверки в сервисе
+		if current.Status.Terminal() {
+			return models.ErrRequestClosed
+		}
+		previous := current.EmployeeId
+
+		query := `UPDATE "request" SET employee_id = $1, version = version + 1, updated_at = NOW() WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, employee_id, id); err != nil {
+			return err
+		}
+
+		event := &models.RequestEvent{
+			RequestId: id,
+			Type:      models.EventEmployeeAssigned,
+			Actor:     actor,
+			NewValue:  eventValue(strconv.FormatInt(employee_id, 10)),
+		}
+		if previous.Valid {
+			event.OldValue = eventValue(strconv.FormatInt(previous.Int64, 10))
+		}
+		return insertRequestEvent(ctx, tx, event)
+	})
+}
+
+// UpdateStatus переводит заявку из статуса from в статус to.
+// Если статус заявки уже не равен from, возвращает models.ErrStatusChanged
+func (r *requestRepository) UpdateStatus(ctx context.Context, id int64, from models.RequestStatus, to models.RequestStatus, actor models.Actor) error {
+	query := `UPDATE "request"
+			  SET status = $1,
+			      version = version + 1,
+			      updated_at = NOW(),
+			      closed_at = CASE WHEN $2 THEN NOW() ELSE NULL END
+			  WHERE id = $3 AND status = $4`
+
+	return withTx(ctx, r.conn, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, to, to.Terminal(), id, from)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return models.ErrStatusChanged
+		}
+
+		return insertRequestEvent(ctx, tx, &models.RequestEvent{
+			RequestId: id,
+			Type:      models.EventStatusChanged,
+			Actor:     actor,
+			OldValue:  eventValue(from.String()),
+			NewValue:  eventValue(to.String()),
+		})
+	})
+}
+
+// UpdatePriority меняет приоритет заявки. Если заявка закрыта, возвращает models.ErrRequestClosed
+func (r *requestRepository) UpdatePriority(ctx context.Context, id int64, priority int16, actor models.Actor) error {
+	return withTx(ctx, r.conn, func(tx *sqlx.Tx) error {
+		var current struct {
+			Priority int16                `db:"priority"`
+			Status   models.RequestStatus `db:"status"`
+		}
+		if err := tx.GetContext(ctx, &current, `SELECT priority, status FROM "request" WHERE id = $1 FOR UPDATE`, id); err != nil {
+			return err
+		}
+		if current.Status.Terminal() {
+			return models.ErrRequestClosed
+		}
+		previous := current.Priority
+
+		query := `UPDATE "request" SET priority = $1, version = version + 1, updated_at = NOW() WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, priority, id); err != nil {
+			return err
+		}
+
+		return insertRequestEvent(ctx, tx, &models.RequestEvent{
+			RequestId: id,
+			Type:      models.EventPriorityChanged,
+			Actor:     actor,
+			OldValue:  eventValue(strconv.Itoa(int(previous))),
+			NewValue:  eventValue(strconv.Itoa(int(priority))),
+		})
+	})
+}
+
+func (r *requestRepository) AddComment(ctx context.Context, id int64, comment string, actor models.Actor, event *models.RequestEvent) error {
+	return withTx(ctx, r.conn, func(tx *sqlx.Tx) error {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM "request" WHERE id = $1)`, id); err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+
+		*event = models.RequestEvent{
+			RequestId: id,
+			Type:      models.EventComment,
+			Actor:     actor,
+			Comment:   &comment,
+		}
+		return insertRequestEvent(ctx, tx, event)
+	})
+}
+
+// GetHistory возвращает события заявки в хронологическом порядке
+func (r *requestRepository) GetHistory(ctx context.Context, id int64, events *[]models.RequestEvent) error {
+	query := `
+		SELECT
+			e.id,
+			e.request_id,
+			e.type,
+			e.actor_type AS "actor.type",
+			e.actor_id   AS "actor.id",
+			e.old_value,
+			e.new_value,
+			e.comment,
+			e.created_at
+		FROM "request_event" e
+		WHERE e.request_id = $1
+		ORDER BY e.created_at, e.id
+	`
+	return r.conn.SelectContext(ctx, events, query, id)
+}
+
+func insertRequestEvent(ctx context.Context, tx *sqlx.Tx, event *models.RequestEvent) error {
+	query := `INSERT INTO "request_event" (request_id, type, actor_type, actor_id, old_value, new_value, comment)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  RETURNING id, created_at`
+
+	return tx.QueryRowxContext(
+		ctx,
+		query,
+		event.RequestId,
+		event.Type,
+		event.Actor.Type,
+		event.Actor.Id,
+		event.OldValue,
+		event.NewValue,
+		event.Comment,
+	).Scan(&event.Id, &event.CreatedAt)
 }
 
-func (r *requestRepository) UpdateStatus(ctx context.Context, id int64, status int16) error {
-	query := `UPDATE request SET status = $1 WHERE id = $2`
-	_, err := r.conn.ExecContext(ctx, query, status, id)
-	return err
+func eventValue(value string) *string {
+	return &value
 }
 
 func (r *requestRepository) Delete(c context.Context, id int) error {
@@ -23,7 +23,7 @@ func (r *roleRepository) Create(c context.Context, role *models.Role) error {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	if err := tx.GetContext(c, role, "INSERT INTO role (name) VALUES ($1) RETURNING *", role.Name); err != nil {
+	if err := tx.GetContext(c, role, "INSERT INTO role (name, mfa_required) VALUES ($1, $2) RETURNING *", role.Name, role.MfaRequired); err != nil {
 		// Отменяем транзакцию, в случае возникнования ошибки
 		if rollbackError := tx.Rollback(); rollbackError != nil {
 			return fmt.Errorf("failed to rollback transaction: %w", rollbackError)
@@ -62,26 +62,18 @@ func (r *roleRepository) GetById(c context.Context, id int, role *models.Role) e
 	return nil
 }
 
-func (r *roleRepository) SetSuperRole(c context.Context, id int) error {
-	var count int
-	err := r.conn.GetContext(c, &count, `SELECT COUNT(*) FROM setting`)
+func (r *roleRepository) SetSuperRoleIfUnset(c context.Context, id int) (bool, error) {
+	result, err := r.conn.ExecContext(c,
+		`UPDATE "setting" SET "superuser_role_id" = $1, "updated_at" = NOW() WHERE "superuser_role_id" IS NULL`, id)
 	if err != nil {
-		return err
-	}
-
-	if count != 0 {
-		_, err = r.conn.ExecContext(c, `UPDATE setting SET superuser_role_id = $1`, id)
-		if err != nil {
-			return err
-		}
+		return false, err
 	}
 
-	_, err = r.conn.ExecContext(c, `INSERT INTO setting (superuser_role_id) VALUES ($1)`, id)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	return nil
+	return rowsAffected != 0, nil
 }
 
 func (r *roleRepository) GetSuperRole(c context.Context, role *models.Role) error {
@@ -92,8 +84,47 @@ func (r *roleRepository) GetSuperRole(c context.Context, role *models.Role) erro
 	return nil
 }
 
+func (r *roleRepository) IsSuperRole(c context.Context, id int) (bool, error) {
+	var super bool
+	err := r.conn.GetContext(c, &super, `SELECT EXISTS (SELECT 1 FROM "setting" WHERE "superuser_role_id" = $1)`, id)
+	if err != nil {
+		return false, err
+	}
+	return super, nil
+}
+
+func (r *roleRepository) GetPermissions(c context.Context, id int, permissions *[]models.Permission) error {
+	return r.conn.SelectContext(c, permissions,
+		`SELECT "permission" FROM "role_permission" WHERE "role_id" = $1 ORDER BY "permission"`, id)
+}
+
+// SetPermissions заменяет набор прав роли целиком
+func (r *roleRepository) SetPermissions(c context.Context, id int, permissions []models.Permission) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		// блокируем роль, чтобы параллельные изменения прав не перемешались
+		var roleId int
+		if err := tx.GetContext(c, &roleId, `SELECT "id" FROM "role" WHERE "id" = $1 FOR UPDATE`, id); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(c, `DELETE FROM "role_permission" WHERE "role_id" = $1`, id); err != nil {
+			return err
+		}
+
+		for _, permission := range permissions {
+			if _, err := tx.ExecContext(c,
+				`INSERT INTO "role_permission" ("role_id", "permission") VALUES ($1, $2)`, id, permission,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *roleRepository) Update(c context.Context, role *models.Role) error {
-	return r.conn.GetContext(c, role, "UPDATE role SET name = $1, updated_at = NOW() WHERE id = $2 RETURNING *;", role.Name, role.Id)
+	query := "UPDATE role SET name = $1, mfa_required = $2, updated_at = NOW() WHERE id = $3 RETURNING *;"
+	return r.conn.GetContext(c, role, query, role.Name, role.MfaRequired, role.Id)
 }
 
 func (r *roleRepository) Delete(c context.Context, id int) error {
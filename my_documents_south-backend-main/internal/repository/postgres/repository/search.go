@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// headlineOptions параметры ts_headline для фрагментов заявок и сообщений
+const headlineOptions = "StartSel=" + models.SnippetStart + ", StopSel=" + models.SnippetStop +
+	`, MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`
+
+// userFullNameExpr совпадает с выражением индекса user_full_name_trgm_idx
+const userFullNameExpr = `LOWER(u.last_name || ' ' || u.name || ' ' || COALESCE(u.middle_name, ''))`
+
+type searchRepository struct {
+	conn *sqlx.DB
+}
+
+func NewSearchRepository(db *sqlx.DB) models.SearchRepository {
+	return &searchRepository{conn: db}
+}
+
+func (r *searchRepository) SearchRequests(ctx context.Context, query string, scope models.SearchScope, limit int, hits *[]models.SearchHit) error {
+	args := []any{query, headlineOptions, limit}
+	where := "r.search @@ q"
+	if scope.EmployeeId != nil {
+		args = append(args, *scope.EmployeeId)
+		where += fmt.Sprintf(" AND r.employee_id = $%d", len(args))
+	}
+
+	return r.conn.SelectContext(ctx, hits, `
+		SELECT
+			r.id,
+			r.name AS title,
+			ts_headline('russian', r.name || ' ' || r."desc", q, $2) AS snippet,
+			ts_rank(r.search, q) AS rank
+		FROM "request" r
+		CROSS JOIN websearch_to_tsquery('russian', $1) q
+		WHERE `+where+`
+		ORDER BY rank DESC, r.id DESC
+		LIMIT $3`, args...)
+}
+
+func (r *searchRepository) SearchMessages(ctx context.Context, query string, scope models.SearchScope, limit int, hits *[]models.SearchHit) error {
+	args := []any{query, headlineOptions, limit}
+	where := "m.search @@ q"
+	if scope.EmployeeId != nil {
+		args = append(args, *scope.EmployeeId)
+		where += fmt.Sprintf(" AND r.employee_id = $%d", len(args))
+	}
+
+	return r.conn.SelectContext(ctx, hits, `
+		SELECT
+			m.id,
+			m.request_id,
+			r.name AS title,
+			ts_headline('russian', m.body, q, $2) AS snippet,
+			ts_rank(m.search, q) AS rank
+		FROM "message" m
+		INNER JOIN "request" r ON m.request_id = r.id
+		CROSS JOIN websearch_to_tsquery('russian', $1) q
+		WHERE `+where+`
+		ORDER BY rank DESC, m.id DESC
+		LIMIT $3`, args...)
+}
+
+// SearchUsers ищет клиентов по фрагменту ФИО, а при непустом digits — по фрагменту телефона, ИНН и СНИЛС.
+// Snippet содержит поле, в котором найдено совпадение, без выделения. query передаётся в триграммные
+// функции как есть, pattern — тот же запрос, экранированный для LIKE; digits не должен содержать символов LIKE
+func (r *searchRepository) SearchUsers(ctx context.Context, query string, pattern string, digits string, limit int, hits *[]models.SearchHit) error {
+	return r.conn.SelectContext(ctx, hits, `
+		SELECT
+			u.id,
+			u.last_name || ' ' || u.name || COALESCE(' ' || u.middle_name, '') AS title,
+			CASE
+				WHEN $2 <> '' AND u.phone LIKE '%' || $2 || '%' THEN u.phone
+				WHEN $2 <> '' AND u.inn LIKE '%' || $2 || '%' THEN u.inn
+				WHEN $2 <> '' AND u.snils LIKE '%' || $2 || '%' THEN u.snils
+				ELSE u.last_name || ' ' || u.name || COALESCE(' ' || u.middle_name, '')
+			END AS snippet,
+			GREATEST(
+				word_similarity(LOWER($1), `+userFullNameExpr+`),
+				CASE WHEN $2 <> '' AND (u.phone LIKE '%' || $2 || '%' OR u.inn LIKE '%' || $2 || '%' OR u.snils LIKE '%' || $2 || '%')
+					THEN 1 ELSE 0 END
+			) AS rank
+		FROM "user" u
+		WHERE `+userFullNameExpr+` LIKE '%' || LOWER($4) || '%'
+		   OR LOWER($1) <% `+userFullNameExpr+`
+		   OR ($2 <> '' AND (u.phone LIKE '%' || $2 || '%' OR u.inn LIKE '%' || $2 || '%' OR u.snils LIKE '%' || $2 || '%'))
+		ORDER BY rank DESC, u.id
+		LIMIT $3`, query, digits, limit, pattern)
+}
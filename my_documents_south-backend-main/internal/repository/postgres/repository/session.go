@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type sessionRepository struct {
+	conn *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) models.SessionRepository {
+	return &sessionRepository{conn: db}
+}
+
+const sessionColumns = `
+	s.id,
+	s.subject_type AS "subject.type",
+	s.subject_id   AS "subject.id",
+	s.user_agent,
+	s.ip,
+	s.expires_at,
+	s.last_used_at,
+	s.revoked_at,
+	s.created_at
+`
+
+func (r *sessionRepository) Create(c context.Context, session *models.Session) error {
+	query := `INSERT INTO "session" (id, subject_type, subject_id, user_agent, ip, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING last_used_at, created_at`
+
+	return r.conn.QueryRowxContext(
+		c,
+		query,
+		session.Id,
+		session.Subject.Type,
+		session.Subject.Id,
+		session.UserAgent,
+		session.Ip,
+		session.ExpiresAt,
+	).Scan(&session.LastUsedAt, &session.CreatedAt)
+}
+
+func (r *sessionRepository) GetById(c context.Context, id string, session *models.Session) error {
+	query := `SELECT ` + sessionColumns + ` FROM "session" s WHERE s.id = $1`
+	return r.conn.GetContext(c, session, query, id)
+}
+
+func (r *sessionRepository) GetActive(c context.Context, subject models.Actor, sessions *[]models.Session) error {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM "session" s
+		WHERE s.subject_type = $1
+		  AND s.subject_id = $2
+		  AND s.revoked_at IS NULL
+		  AND s.expires_at > NOW()
+		ORDER BY s.last_used_at DESC
+	`
+	return r.conn.SelectContext(c, sessions, query, subject.Type, subject.Id)
+}
+
+func (r *sessionRepository) IsActive(c context.Context, id string) (bool, error) {
+	var active bool
+	query := `SELECT EXISTS (SELECT 1 FROM "session" WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW())`
+	if err := r.conn.GetContext(c, &active, query, id); err != nil {
+		return false, err
+	}
+	return active, nil
+}
+
+func (r *sessionRepository) Touch(c context.Context, id string, client models.SessionClient, expiresAt time.Time) error {
+	query := `UPDATE "session"
+			  SET user_agent = $1, ip = $2, expires_at = $3, last_used_at = NOW()
+			  WHERE id = $4`
+	_, err := r.conn.ExecContext(c, query, client.UserAgent, client.Ip, expiresAt, id)
+	return err
+}
+
+func (r *sessionRepository) Revoke(c context.Context, id string) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(c,
+			`UPDATE "session" SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return models.ErrSessionNotFound
+		}
+
+		_, err = tx.ExecContext(c,
+			`UPDATE "refresh_token" SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, id)
+		return err
+	})
+}
+
+func (r *sessionRepository) RevokeSubject(c context.Context, subject models.Actor) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(c, `
+			UPDATE "session"
+			SET revoked_at = NOW()
+			WHERE subject_type = $1 AND subject_id = $2 AND revoked_at IS NULL
+		`, subject.Type, subject.Id); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(c, `
+			UPDATE "refresh_token"
+			SET revoked_at = NOW()
+			WHERE subject_type = $1 AND subject_id = $2 AND revoked_at IS NULL
+		`, subject.Type, subject.Id)
+		return err
+	})
+}
+
+func (r *sessionRepository) RevokeOthers(c context.Context, subject models.Actor, currentId string) error {
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(c, `
+			UPDATE "session"
+			SET revoked_at = NOW()
+			WHERE subject_type = $1 AND subject_id = $2 AND id <> $3 AND revoked_at IS NULL
+		`, subject.Type, subject.Id, currentId); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(c, `
+			UPDATE "refresh_token"
+			SET revoked_at = NOW()
+			WHERE subject_type = $1 AND subject_id = $2 AND family_id <> $3 AND revoked_at IS NULL
+		`, subject.Type, subject.Id, currentId)
+		return err
+	})
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const settingColumns = `"default_tariff_id", "superuser_role_id", "signup_open", "max_upload_size",
+	"sla_default_priority", "sla_resolution_hours", "support_email", "support_phone", "updated_at"`
+
+type settingRepository struct {
+	conn *sqlx.DB
+}
+
+func NewSettingRepository(db *sqlx.DB) models.SettingRepository {
+	return &settingRepository{conn: db}
+}
+
+func (r *settingRepository) Get(c context.Context, settings *models.Settings) error {
+	return r.conn.GetContext(c, settings, `SELECT `+settingColumns+` FROM "setting"`)
+}
+
+func (r *settingRepository) Update(c context.Context, settings *models.Settings) error {
+	query := `UPDATE "setting" SET
+				"default_tariff_id" = $1,
+				"superuser_role_id" = $2,
+				"signup_open" = $3,
+				"max_upload_size" = $4,
+				"sla_default_priority" = $5,
+				"sla_resolution_hours" = $6,
+				"support_email" = $7,
+				"support_phone" = $8,
+				"updated_at" = NOW()
+			  RETURNING ` + settingColumns
+	return r.conn.GetContext(
+		c,
+		settings,
+		query,
+		settings.DefaultTariffId,
+		settings.SuperuserRoleId,
+		settings.SignupOpen,
+		settings.MaxUploadSize,
+		settings.SLADefaultPriority,
+		settings.SLAResolutionHours,
+		settings.SupportEmail,
+		settings.SupportPhone,
+	)
+}
@@ -62,26 +62,18 @@ func (r *tariffRepository) GetById(c context.Context, id int, tariff *models.Tar
 	return nil
 }
 
-func (r *tariffRepository) SetDefault(c context.Context, id int) error {
-	var count int
-	err := r.conn.GetContext(c, &count, `SELECT COUNT(*) FROM setting`)
+func (r *tariffRepository) SetDefaultIfUnset(c context.Context, id int) (bool, error) {
+	result, err := r.conn.ExecContext(c,
+		`UPDATE "setting" SET "default_tariff_id" = $1, "updated_at" = NOW() WHERE "default_tariff_id" IS NULL`, id)
 	if err != nil {
-		return err
-	}
-
-	if count != 0 {
-		_, err = r.conn.ExecContext(c, `UPDATE setting SET default_tariff_id = $1`, id)
-		if err != nil {
-			return err
-		}
+		return false, err
 	}
 
-	_, err = r.conn.ExecContext(c, `INSERT INTO setting (default_tariff_id) VALUES ($1)`, id)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	return nil
+	return rowsAffected != 0, nil
 }
 
 func (r *tariffRepository) GetDefault(c context.Context, tariff *models.Tariff) error {
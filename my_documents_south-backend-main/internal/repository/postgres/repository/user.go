@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"my_documents_south_backend/internal/models"
@@ -9,6 +10,11 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// userColumns поля клиента без хеша пароля. middle_name, inn и tariff_id в схеме допускают NULL
+const userColumns = `u.id, u.name, u.last_name, COALESCE(u.middle_name, '') AS middle_name, u.email, u.phone,
+	u.phone_verified_at, u.email_verified_at, COALESCE(u.tariff_id, 0) AS tariff_id,
+	COALESCE(u.inn, '') AS inn, u.snils, u.created_at, u.updated_at`
+
 type userRepository struct {
 	conn *sqlx.DB
 }
@@ -28,6 +34,19 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
+	var taken bool
+	if err := tx.GetContext(ctx, &taken,
+		`SELECT EXISTS(SELECT 1 FROM "user" WHERE LOWER(email) = LOWER($1) OR phone = $2)`, user.Email, user.Phone,
+	); err != nil || taken {
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			return fmt.Errorf("failed to rollback transaction: %w", rollbackError)
+		}
+		if err != nil {
+			return err
+		}
+		return models.ErrUserExists
+	}
+
 	if err := tx.GetContext(
 		ctx,
 		user,
@@ -65,21 +84,12 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 }
 
 func (r *userRepository) Get(c context.Context, user *[]models.User) error {
-	query := `SELECT 
-				u.id,
-				u.name,
-				u.last_name,
-				u.middle_name,
-				u.email,
-				u.phone,
-				u.inn,
-				u.snils,
-				u.created_at,
-				u.updated_at,
-				t.id AS "tariff.id",
-				t.name AS "tariff.name"
+	query := `SELECT ` + userColumns + `,
+				COALESCE(t.id, 0) AS "tariff.id",
+				COALESCE(t.name, '') AS "tariff.name"
 			  FROM "user" u
-		      LEFT JOIN "tariff" t ON u.tariff_id = t.id`
+			  LEFT JOIN "tariff" t ON u.tariff_id = t.id
+			  ORDER BY u.id`
 
 	err := r.conn.SelectContext(c, user, query)
 	if err != nil {
@@ -89,28 +99,95 @@ func (r *userRepository) Get(c context.Context, user *[]models.User) error {
 }
 
 func (r *userRepository) GetById(c context.Context, id int, user *models.User) error {
-	err := r.conn.GetContext(c, user, `SELECT * FROM "user" WHERE id = $1`, id)
-	if err != nil {
-		return errors.New("user not found by id")
+	err := r.conn.GetContext(c, user, `SELECT `+userColumns+` FROM "user" u WHERE u.id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrUserNotFound
 	}
+	return err
+}
 
-	return nil
+// GetByIds загружает клиентов с указанными id одним запросом, отсутствующие id пропускаются
+func (r *userRepository) GetByIds(c context.Context, ids []int64, users *[]models.User) error {
+	return r.conn.SelectContext(c, users, `SELECT `+userColumns+` FROM "user" u WHERE u.id = ANY($1)`, ids)
 }
 
 func (r *userRepository) GetByPhone(c context.Context, phone string, user *models.User) error {
 	err := r.conn.GetContext(c, user, `SELECT * FROM "user" WHERE "phone" = $1`, phone)
 	if err != nil {
-		return errors.New("user not fount by phone")
+		return fmt.Errorf("user not found by phone: %w", err)
 	}
 
 	return nil
 }
 
-// Update TODO
+// SetPhoneVerified отмечает телефон клиента подтверждённым
+func (r *userRepository) SetPhoneVerified(c context.Context, id int64) error {
+	query := `UPDATE "user" SET phone_verified_at = NOW() WHERE id = $1 AND phone_verified_at IS NULL`
+	_, err := r.conn.ExecContext(c, query, id)
+	return err
+}
+
+// Update сохраняет имя, контакты, ИНН и СНИЛС. Смена почты или телефона сбрасывает их подтверждение
 func (r *userRepository) Update(c context.Context, user *models.User) error {
-	// TODO update user repository
-	// DONT TOUCH
-	return nil
+	return withTx(c, r.conn, func(tx *sqlx.Tx) error {
+		var taken bool
+		if err := tx.GetContext(c, &taken,
+			`SELECT EXISTS(SELECT 1 FROM "user" WHERE (LOWER(email) = LOWER($1) OR phone = $2) AND id <> $3)`,
+			user.Email, user.Phone, user.Id); err != nil {
+			return err
+		}
+		if taken {
+			return models.ErrUserExists
+		}
+
+		query := `UPDATE "user" u SET
+					name = $1,
+					last_name = $2,
+					middle_name = $3,
+					email = $4,
+					phone = $5,
+					inn = NULLIF($6, ''),
+					snils = $7,
+					email_verified_at = CASE WHEN LOWER(u.email) = LOWER($4) THEN u.email_verified_at END,
+					phone_verified_at = CASE WHEN u.phone = $5 THEN u.phone_verified_at END,
+					updated_at = NOW()
+				  WHERE u.id = $8
+				  RETURNING ` + userColumns
+		err := tx.GetContext(
+			c,
+			user,
+			query,
+			user.Name,
+			user.LastName,
+			user.MiddleName,
+			user.Email,
+			user.Phone,
+			user.Inn,
+			user.Snils,
+			user.Id,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrUserNotFound
+		}
+		return err
+	})
+}
+
+func (r *userRepository) GetPassword(c context.Context, id int64) (string, error) {
+	var hash string
+	err := r.conn.GetContext(c, &hash, `SELECT password FROM "user" WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", models.ErrUserNotFound
+	}
+	return hash, err
+}
+
+func (r *userRepository) SetPassword(c context.Context, id int64, hash string) error {
+	result, err := r.conn.ExecContext(c, `UPDATE "user" SET password = $1, updated_at = NOW() WHERE id = $2`, hash, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result, models.ErrUserNotFound)
 }
 
 func (r *userRepository) Delete(c context.Context, id int) error {
@@ -125,7 +202,7 @@ func (r *userRepository) Delete(c context.Context, id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("user not found")
+		return models.ErrUserNotFound
 	}
 	return nil
 }
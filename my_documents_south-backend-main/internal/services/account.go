@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"my_documents_south_backend/internal/models"
+	"time"
+
+	"github.com/dongri/phonenumber"
+)
+
+// account клиент или сотрудник, найденный по данным для входа
+type account struct {
+	subject         models.Actor
+	email           string
+	emailVerifiedAt *time.Time
+}
+
+// findAccount ищет клиента по телефону, если он указан, иначе сотрудника по почте
+func findAccount(
+	ctx context.Context,
+	userRepository models.UserRepository,
+	employeeRepository models.EmployeeRepository,
+	phone string,
+	email string,
+) (*account, error) {
+	if phone != "" {
+		var user models.User
+		if err := userRepository.GetByPhone(ctx, phonenumber.Parse(phone, "RU"), &user); err != nil {
+			return nil, err
+		}
+		return &account{
+			subject:         models.Actor{Type: models.ActorUser, Id: user.Id},
+			email:           user.Email,
+			emailVerifiedAt: user.EmailVerifiedAt,
+		}, nil
+	}
+
+	var employee models.Employee
+	if err := employeeRepository.GetByEmail(ctx, email, &employee); err != nil {
+		return nil, err
+	}
+	return &account{
+		subject:         models.Actor{Type: models.ActorEmployee, Id: employee.Id},
+		email:           employee.Email,
+		emailVerifiedAt: employee.EmailVerifiedAt,
+	}, nil
+}
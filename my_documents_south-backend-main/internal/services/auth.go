@@ -2,119 +2,445 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/dongri/phonenumber"
-	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"my_documents_south_backend/internal/config"
 	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/utils/password"
 	"time"
+	"unicode/utf8"
 )
 
 type AuthService struct {
-	employeeRepository models.EmployeeRepository
-	userRepository     models.UserRepository
-	contextTimeout     time.Duration
+	employeeRepository     models.EmployeeRepository
+	userRepository         models.UserRepository
+	refreshTokenRepository models.RefreshTokenRepository
+	sessionRepository      models.SessionRepository
+	otpService             *OTPService
+	emailVerification      *EmailVerificationService
+	mfaService             *MFAService
+	loginGuard             *LoginGuard
+	jwtConfig              config.JWT
+	contextTimeout         time.Duration
 }
 
 func NewAuthService(
 	employeeRepository models.EmployeeRepository,
 	userRepository models.UserRepository,
+	refreshTokenRepository models.RefreshTokenRepository,
+	sessionRepository models.SessionRepository,
+	otpService *OTPService,
+	emailVerification *EmailVerificationService,
+	mfaService *MFAService,
+	loginGuard *LoginGuard,
+	jwtConfig config.JWT,
 	contextTimeout time.Duration,
 ) *AuthService {
 	return &AuthService{
-		employeeRepository: employeeRepository,
-		userRepository:     userRepository,
-		contextTimeout:     contextTimeout,
+		employeeRepository:     employeeRepository,
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		sessionRepository:      sessionRepository,
+		otpService:             otpService,
+		emailVerification:      emailVerification,
+		mfaService:             mfaService,
+		loginGuard:             loginGuard,
+		jwtConfig:              jwtConfig,
+		contextTimeout:         contextTimeout,
 	}
 }
 
-func (s *AuthService) LoginEmployee(c context.Context, input *models.Employee) (*models.JwtToken, error) {
+// LoginEmployee вход сотрудника по почте и паролю. Если у сотрудника подключен второй фактор
+// или он обязателен для роли, вместо токенов возвращается вызов на проверку второго фактора
+func (s *AuthService) LoginEmployee(c context.Context, input *models.Employee, client models.SessionClient) (*models.JwtToken, *models.MFAChallengeResponse, error) {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
+	account := employeeAccount(input.Email)
+	if err := s.loginGuard.Check(ctx, client.Ip, account); err != nil {
+		return nil, nil, err
+	}
+
 	// Поиск сотрудника по почте
 	var employee models.Employee
 	err := s.employeeRepository.GetByEmail(ctx, input.Email, &employee)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, s.loginFailed(ctx, client.Ip, account, err)
+		}
+		return nil, nil, err
 	}
 
 	// Сравнение паролей
 	if err := password.Compare(employee.Password, input.Password); err != nil {
-		return nil, fmt.Errorf("invalid password")
+		return nil, nil, s.loginFailed(ctx, client.Ip, account, fmt.Errorf("invalid password"))
+	}
+
+	// проверяется после пароля, чтобы не раскрывать статус аккаунта без него
+	if !employee.Active {
+		return nil, nil, models.ErrEmployeeInactive
+	}
+
+	if err := s.emailVerification.CheckLogin(employee.EmailVerifiedAt); err != nil {
+		return nil, nil, err
+	}
+
+	challenge, err := s.mfaService.StartChallenge(ctx, &employee)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		// попытки аккаунта сбрасываются только после проверки второго фактора
+		return nil, challenge, nil
 	}
 
-	// Получение ключа доступа на 24 часа
-	accessToken, err := middleware.JWTGenerate(employee.Id, &employee.RoleId, time.Hour)
+	if err := s.loginGuard.Succeed(ctx, account); err != nil {
+		return nil, nil, err
+	}
+
+	roleID := employee.RoleId
+	token, err := s.startSession(ctx, &models.Principal{Type: models.ActorEmployee, Id: employee.Id, RoleId: &roleID}, client)
+	return token, nil, err
+}
+
+// VerifyMFA завершает вход сотрудника проверкой второго фактора
+func (s *AuthService) VerifyMFA(c context.Context, mfaToken string, code string, client models.SessionClient) (*models.MFAResult, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	employeeId, err := s.mfaService.ChallengeEmployee(ctx, mfaToken)
 	if err != nil {
 		return nil, err
 	}
 
-	// Получение ключа для продления на 7 дней
-	refreshToken, err := middleware.JWTGenerate(employee.Id, &employee.RoleId, time.Hour*24*7)
+	employee, err := s.employeeRepository.GetByIdWithServices(ctx, employeeId)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.JwtToken{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+	// неверные коды блокируют вход так же, как неверный пароль, иначе код можно подбирать, входя заново
+	account := employeeAccount(employee.Email)
+	if err := s.loginGuard.Check(ctx, client.Ip, account); err != nil {
+		return nil, err
+	}
+
+	_, recoveryCodes, err := s.mfaService.VerifyChallenge(ctx, mfaToken, code)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidMFACode) {
+			return nil, s.loginFailed(ctx, client.Ip, account, err)
+		}
+		return nil, err
+	}
+
+	if !employee.Active {
+		return nil, models.ErrEmployeeInactive
+	}
+
+	if err := s.loginGuard.Succeed(ctx, account); err != nil {
+		return nil, err
+	}
+
+	roleID := employee.RoleId
+	token, err := s.startSession(ctx, &models.Principal{Type: models.ActorEmployee, Id: employee.Id, RoleId: &roleID}, client)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MFAResult{JwtToken: *token, RecoveryCodes: recoveryCodes}, nil
 }
 
-func (s *AuthService) LoginUser(c context.Context, input *models.User) (*models.JwtToken, error) {
+func (s *AuthService) LoginUser(c context.Context, input *models.User, client models.SessionClient) (*models.JwtToken, error) {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
+	phone := phonenumber.Parse(input.Phone, "RU")
+	account := userAccount(phone)
+	if phone == "" {
+		account = userAccount(input.Phone)
+	}
+	if err := s.loginGuard.Check(ctx, client.Ip, account); err != nil {
+		return nil, err
+	}
+
 	// Поиск пользователя по номеру
 	var user models.User
-	err := s.userRepository.GetByPhone(ctx, phonenumber.Parse(input.Phone, "RU"), &user)
+	err := s.userRepository.GetByPhone(ctx, phone, &user)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, s.loginFailed(ctx, client.Ip, account, err)
+		}
 		return nil, err
 	}
 
 	// Сравнение паролей
 	if err := password.Compare(user.Password, input.Password); err != nil {
-		return nil, fmt.Errorf("invalid password")
+		return nil, s.loginFailed(ctx, client.Ip, account, fmt.Errorf("invalid password"))
+	}
+
+	if err := s.emailVerification.CheckLogin(user.EmailVerifiedAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.loginGuard.Succeed(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, &models.Principal{Type: models.ActorUser, Id: user.Id}, client)
+}
+
+// LoginUserOTP вход клиента по коду из SMS. Успешный вход подтверждает телефон клиента
+func (s *AuthService) LoginUserOTP(c context.Context, phone string, code string, client models.SessionClient) (*models.JwtToken, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.otpService.Verify(ctx, phone, models.OTPLogin, code); err != nil {
+		return nil, err
 	}
 
-	// Получение ключа доступа на час
-	accessToken, err := middleware.JWTGenerate(user.Id, nil, time.Hour)
+	var user models.User
+	if err := s.userRepository.GetByPhone(ctx, phonenumber.Parse(phone, "RU"), &user); err != nil {
+		return nil, err
+	}
+
+	if err := s.emailVerification.CheckLogin(user.EmailVerifiedAt); err != nil {
+		return nil, err
+	}
+
+	if user.PhoneVerifiedAt == nil {
+		if err := s.userRepository.SetPhoneVerified(ctx, user.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.startSession(ctx, &models.Principal{Type: models.ActorUser, Id: user.Id}, client)
+}
+
+// RefreshToken обменивает refresh токен на новую пару токенов. Каждый refresh токен одноразовый:
+// повторное предъявление уже использованного токена отзывает всю сессию
+func (s *AuthService) RefreshToken(c context.Context, refreshToken string, client models.SessionClient) (*models.JwtToken, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	principal, jti, err := middleware.ParseRefreshToken(s.jwtConfig.Secret, refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
-	// Получение ключа для продления на 7 дней
-	refreshToken, err := middleware.JWTGenerate(user.Id, nil, time.Hour*24*7)
+	var stored models.RefreshToken
+	if err := s.refreshTokenRepository.GetById(ctx, jti, &stored); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.Subject != principal.Actor() || stored.FamilyId != principal.SessionId {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	if stored.RotatedAt != nil {
+		return nil, s.revokeReused(ctx, stored.FamilyId)
+	}
+	if !stored.Active(time.Now()) {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	// роль в токенах берётся из БД, чтобы смена роли и деактивация доходили до новых access токенов
+	if principal.IsEmployee() {
+		var employee models.Employee
+		if err := s.employeeRepository.GetById(ctx, int(principal.Id), &employee); err != nil {
+			if errors.Is(err, models.ErrEmployeeNotFound) {
+				return nil, models.ErrInvalidRefreshToken
+			}
+			return nil, err
+		}
+		if !employee.Active {
+			return nil, models.ErrInvalidRefreshToken
+		}
+		roleID := employee.RoleId
+		principal.RoleId = &roleID
+	}
+
+	token, next, err := s.issueTokens(principal)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.JwtToken{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+	if err := s.refreshTokenRepository.Rotate(ctx, jti, next); err != nil {
+		if errors.Is(err, models.ErrRefreshTokenReused) {
+			return nil, s.revokeReused(ctx, stored.FamilyId)
+		}
+		return nil, err
+	}
+
+	if err := s.sessionRepository.Touch(ctx, principal.SessionId, limitClient(client), next.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Logout завершает текущую сессию: её refresh и access токены перестают действовать
+func (s *AuthService) Logout(c context.Context, principal *models.Principal) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.sessionRepository.Revoke(ctx, principal.SessionId); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+		return err
+	}
+	return nil
 }
 
-func (s *AuthService) RefreshToken(userID int64, roleID *int, token *models.JwtToken) error {
-	// Парсим и проверяем refresh token
-	var new_token, err = jwt.Parse(token.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.ErrUnauthorized
+// LogoutAll завершает все сессии клиента или сотрудника
+func (s *AuthService) LogoutAll(c context.Context, principal *models.Principal) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.sessionRepository.RevokeSubject(ctx, principal.Actor())
+}
+
+// GetSessions возвращает активные сессии субъекта. Сессия currentId отмечается как текущая
+func (s *AuthService) GetSessions(c context.Context, subject models.Actor, currentId string) ([]models.Session, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	sessions := []models.Session{}
+	if err := s.sessionRepository.GetActive(ctx, subject, &sessions); err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == currentId
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию id, если она принадлежит subject
+func (s *AuthService) RevokeSession(c context.Context, subject models.Actor, id string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var session models.Session
+	if err := s.sessionRepository.GetById(ctx, id, &session); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrSessionNotFound
 		}
-		return []byte("my_documents_south_jwt_super_secret_key_for_security"), nil
-	})
+		return err
+	}
 
-	if err != nil || !new_token.Valid {
-		return errors.New("invalid or expired refresh token")
+	if session.Subject != subject {
+		return models.ErrSessionNotFound
 	}
 
-	token.AccessToken, err = middleware.JWTGenerate(userID, roleID, time.Hour)
+	return s.sessionRepository.Revoke(ctx, id)
+}
+
+// startSession открывает новую сессию и выдаёт первую пару токенов
+func (s *AuthService) startSession(ctx context.Context, principal *models.Principal, client models.SessionClient) (*models.JwtToken, error) {
+	sessionID, err := randomID()
 	if err != nil {
-		return errors.New("failed to generate access token")
+		return nil, err
 	}
+	principal.SessionId = sessionID
 
-	token.RefreshToken, err = middleware.JWTGenerate(userID, roleID, 7*24*time.Hour)
+	token, refresh, err := s.issueTokens(principal)
 	if err != nil {
-		return errors.New("failed to generate refresh token")
+		return nil, err
 	}
 
-	return nil
+	client = limitClient(client)
+	session := &models.Session{
+		Id:        sessionID,
+		Subject:   principal.Actor(),
+		UserAgent: client.UserAgent,
+		Ip:        client.Ip,
+		ExpiresAt: refresh.ExpiresAt,
+	}
+	if err := s.sessionRepository.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepository.Create(ctx, refresh); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// issueTokens подписывает access и refresh токены сессии principal.SessionId.
+// Возвращает запись refresh токена, которую нужно сохранить
+func (s *AuthService) issueTokens(principal *models.Principal) (*models.JwtToken, *models.RefreshToken, error) {
+	accessID, err := randomID()
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshID, err := randomID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Получение ключа доступа
+	accessToken, err := middleware.JWTGenerate(s.jwtConfig.Secret, middleware.TokenAccess, principal, accessID, s.jwtConfig.AccessTTL)
+	if err != nil {
+		return nil, nil, errors.New("failed to generate access token")
+	}
+
+	// Получение ключа для продления
+	refreshToken, err := middleware.JWTGenerate(s.jwtConfig.Secret, middleware.TokenRefresh, principal, refreshID, s.jwtConfig.RefreshTTL)
+	if err != nil {
+		return nil, nil, errors.New("failed to generate refresh token")
+	}
+
+	refresh := &models.RefreshToken{
+		Id:        refreshID,
+		FamilyId:  principal.SessionId,
+		Subject:   principal.Actor(),
+		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTTL),
+	}
+	return &models.JwtToken{AccessToken: accessToken, RefreshToken: refreshToken}, refresh, nil
+}
+
+// revokeReused отзывает сессию, в которой повторно использован refresh токен
+func (s *AuthService) revokeReused(ctx context.Context, familyID string) error {
+	if err := s.sessionRepository.Revoke(ctx, familyID); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+		return err
+	}
+	log.Printf("auth: refresh token reuse detected, session %s revoked", familyID)
+	return models.ErrRefreshTokenReused
+}
+
+// loginFailed учитывает неудачный вход по паролю: неизвестный аккаунт или неверный пароль
+func (s *AuthService) loginFailed(ctx context.Context, ip string, account string, err error) error {
+	if failErr := s.loginGuard.Fail(ctx, ip, account); failErr != nil {
+		return failErr
+	}
+	return err
+}
+
+func employeeAccount(email string) string {
+	return string(models.ActorEmployee) + ":" + email
+}
+
+func userAccount(phone string) string {
+	return string(models.ActorUser) + ":" + phone
+}
+
+// limitClient обрезает данные клиента до размера, достаточного для отображения в списке сессий
+func limitClient(client models.SessionClient) models.SessionClient {
+	if utf8.RuneCountInString(client.UserAgent) > 512 {
+		client.UserAgent = string([]rune(client.UserAgent)[:512])
+	}
+	return client
+}
+
+func randomID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
 }
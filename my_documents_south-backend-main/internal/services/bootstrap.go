@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/utils/password"
+	"strings"
+	"time"
+)
+
+type BootstrapService struct {
+	bootstrapRepository models.BootstrapRepository
+	contextTimeout      time.Duration
+}
+
+func NewBootstrapService(bootstrapRepository models.BootstrapRepository, contextTimeout time.Duration) *BootstrapService {
+	return &BootstrapService{bootstrapRepository: bootstrapRepository, contextTimeout: contextTimeout}
+}
+
+// Run создаёт недостающие суперроль, тариф по умолчанию и первого администратора.
+// Пароль администратора передаётся открытым и шифруется здесь
+func (s *BootstrapService) Run(c context.Context, bootstrap *models.Bootstrap) (*models.BootstrapResult, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	bootstrap.RoleName = strings.TrimSpace(bootstrap.RoleName)
+	if bootstrap.RoleName == "" {
+		bootstrap.RoleName = models.DefaultSuperRoleName
+	}
+	bootstrap.TariffName = strings.TrimSpace(bootstrap.TariffName)
+	if bootstrap.TariffName == "" {
+		bootstrap.TariffName = models.DefaultTariffName
+	}
+
+	if admin := bootstrap.Admin; admin != nil {
+		admin.Email = strings.ToLower(strings.TrimSpace(admin.Email))
+		if !emailPattern.MatchString(admin.Email) {
+			return nil, errors.New("invalid email format")
+		}
+		admin.Name = strings.TrimSpace(admin.Name)
+		admin.LastName = strings.TrimSpace(admin.LastName)
+		if admin.Name == "" || admin.LastName == "" {
+			return nil, errors.New("name and last_name are required")
+		}
+
+		if err := password.Validate(admin.Password); err != nil {
+			return nil, err
+		}
+
+		hash, err := password.Encrypt(admin.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt password: %w", err)
+		}
+		admin.Password = hash
+	}
+
+	var result models.BootstrapResult
+	if err := s.bootstrapRepository.Run(ctx, bootstrap, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
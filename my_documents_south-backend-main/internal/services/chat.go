@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"my_documents_south_backend/internal/models"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 200
+	subscriberBuffer       = 32
+)
+
+// chatHub рассылает события чата подписчикам внутри одного процесса.
+// При нескольких экземплярах приложения клиенты получат события только от своего экземпляра
+type chatHub struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[chan models.ChatEvent]struct{}
+}
+
+func newChatHub() *chatHub {
+	return &chatHub{subscribers: map[int64]map[chan models.ChatEvent]struct{}{}}
+}
+
+func (h *chatHub) subscribe(requestId int64) (chan models.ChatEvent, func()) {
+	events := make(chan models.ChatEvent, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[requestId] == nil {
+		h.subscribers[requestId] = map[chan models.ChatEvent]struct{}{}
+	}
+	h.subscribers[requestId][events] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[requestId], events)
+			if len(h.subscribers[requestId]) == 0 {
+				delete(h.subscribers, requestId)
+			}
+			h.mu.Unlock()
+			close(events)
+		})
+	}
+	return events, unsubscribe
+}
+
+func (h *chatHub) publish(event models.ChatEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for events := range h.subscribers[event.RequestId] {
+		select {
+		case events <- event:
+		default:
+			// не блокируем рассылку из-за медленного клиента
+			log.Printf("chat: dropped %s event for request %d, subscriber is too slow", event.Type, event.RequestId)
+		}
+	}
+}
+
+type chatService struct {
+	messageRepository models.MessageRepository
+	hub               *chatHub
+	contextTimeout    time.Duration
+}
+
+func NewChatService(messageRepository models.MessageRepository, contextTimeout time.Duration) models.ChatService {
+	return &chatService{
+		messageRepository: messageRepository,
+		hub:               newChatHub(),
+		contextTimeout:    contextTimeout,
+	}
+}
+
+func (s *chatService) History(c context.Context, requestId int64, actor models.Actor, cursor int64, limit int) (*models.MessagePage, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.checkParticipant(ctx, requestId, actor); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	if limit > maxMessagePageSize {
+		limit = maxMessagePageSize
+	}
+
+	page := &models.MessagePage{Items: []models.Message{}}
+	if err := s.messageRepository.GetPage(ctx, requestId, cursor, limit, &page.Items); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) == limit {
+		next := page.Items[len(page.Items)-1].Id
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+func (s *chatService) Send(c context.Context, requestId int64, actor models.Actor, body string) (*models.Message, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > 4000 {
+		return nil, models.ErrInvalidMessage
+	}
+
+	if err := s.checkParticipant(ctx, requestId, actor); err != nil {
+		return nil, err
+	}
+
+	message := &models.Message{RequestId: requestId, Sender: actor, Body: body}
+	if err := s.messageRepository.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	s.hub.publish(models.ChatEvent{Type: models.ChatEventMessage, RequestId: requestId, Actor: &actor, Message: message})
+	return message, nil
+}
+
+func (s *chatService) MarkRead(c context.Context, requestId int64, actor models.Actor, upTo int64) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.checkParticipant(ctx, requestId, actor); err != nil {
+		return err
+	}
+
+	marked, err := s.messageRepository.MarkRead(ctx, requestId, actor, upTo)
+	if err != nil {
+		return err
+	}
+
+	if marked != 0 {
+		s.hub.publish(models.ChatEvent{Type: models.ChatEventRead, RequestId: requestId, Actor: &actor, UpTo: upTo})
+	}
+	return nil
+}
+
+func (s *chatService) Typing(c context.Context, requestId int64, actor models.Actor) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.checkParticipant(ctx, requestId, actor); err != nil {
+		return err
+	}
+
+	s.hub.publish(models.ChatEvent{Type: models.ChatEventTyping, RequestId: requestId, Actor: &actor})
+	return nil
+}
+
+func (s *chatService) Subscribe(c context.Context, requestId int64, actor models.Actor) (*models.ChatSubscription, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.checkParticipant(ctx, requestId, actor); err != nil {
+		return nil, err
+	}
+
+	events, unsubscribe := s.hub.subscribe(requestId)
+	delivered := make(chan models.ChatEvent, subscriberBuffer)
+	stop := make(chan struct{})
+
+	// доступ проверяется перед каждым событием: после переназначения заявки
+	// прежний исполнитель перестаёт получать события, даже не отправляя команд
+	go func() {
+		defer close(delivered)
+		for event := range events {
+			if err := s.Authorize(context.Background(), requestId, actor); err != nil {
+				if !errors.Is(err, models.ErrForbidden) {
+					log.Printf("chat: failed to check access to request %d: %v", requestId, err)
+				}
+				unsubscribe()
+				return
+			}
+			select {
+			case delivered <- event:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	closeSubscription := func() {
+		once.Do(func() {
+			close(stop)
+			unsubscribe()
+		})
+	}
+	return &models.ChatSubscription{Events: delivered, Close: closeSubscription}, nil
+}
+
+// Authorize доступ к чату есть только у владельца заявки и назначенного сотрудника
+func (s *chatService) Authorize(c context.Context, requestId int64, actor models.Actor) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.checkParticipant(ctx, requestId, actor)
+}
+
+func (s *chatService) checkParticipant(ctx context.Context, requestId int64, actor models.Actor) error {
+	var participants models.ChatParticipants
+	if err := s.messageRepository.GetParticipants(ctx, requestId, &participants); err != nil {
+		return err
+	}
+
+	if !participants.Has(actor) {
+		return models.ErrForbidden
+	}
+	return nil
+}
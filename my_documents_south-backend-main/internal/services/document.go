@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/storage"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// officeMimeTypes MIME типы офисных документов. http.DetectContentType определяет их как zip или
+// octet-stream, поэтому для них тип уточняется по расширению файла
+var officeMimeTypes = map[string]string{
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type documentService struct {
+	documentRepository models.DocumentRepository
+	requestRepository  models.RequestRepository
+	store              storage.BlobStore
+	settings           *SettingService
+	maxUploadSize      int64
+	allowedMimeTypes   map[string]bool
+	contextTimeout     time.Duration
+}
+
+func NewDocumentService(
+	documentRepository models.DocumentRepository,
+	requestRepository models.RequestRepository,
+	store storage.BlobStore,
+	settings *SettingService,
+	maxUploadSize int64,
+	allowedMimeTypes []string,
+	contextTimeout time.Duration,
+) models.DocumentService {
+	allowed := make(map[string]bool, len(allowedMimeTypes))
+	for _, mimeType := range allowedMimeTypes {
+		allowed[mimeType] = true
+	}
+
+	return &documentService{
+		documentRepository: documentRepository,
+		requestRepository:  requestRepository,
+		store:              store,
+		settings:           settings,
+		maxUploadSize:      maxUploadSize,
+		allowedMimeTypes:   allowed,
+		contextTimeout:     contextTimeout,
+	}
+}
+
+func (s *documentService) Upload(c context.Context, requestId int64, upload models.DocumentUpload, actor models.Actor) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	name := filepath.Base(strings.ReplaceAll(upload.Name, "\\", "/"))
+	if name == "." || name == "/" || utf8.RuneCountInString(name) > 255 {
+		return nil, models.ErrDocumentInvalidName
+	}
+
+	if upload.Size <= 0 {
+		return nil, errors.New("document is empty")
+	}
+
+	settings, err := s.settings.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// предел из конфигурации ограничивает и тело запроса, настройка может его только уменьшить
+	maxUploadSize := min(s.maxUploadSize, settings.MaxUploadSize)
+	if upload.Size > maxUploadSize {
+		return nil, models.ErrDocumentTooLarge
+	}
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(requestId), &req); err != nil {
+		return nil, err
+	}
+
+	// Тип определяем по содержимому, а не по заголовку от клиента
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	head = head[:n]
+
+	mimeType := detectMimeType(head, name)
+	if !s.allowedMimeTypes[mimeType] {
+		return nil, fmt.Errorf("%w: %s", models.ErrDocumentType, mimeType)
+	}
+
+	key, err := documentKey(requestId)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	content := io.TeeReader(io.MultiReader(bytes.NewReader(head), upload.Content), hash)
+	counter := &countingReader{r: io.LimitReader(content, maxUploadSize+1)}
+
+	if err := s.store.Put(ctx, key, counter, upload.Size, mimeType); err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+
+	if counter.n != upload.Size {
+		s.removeBlob(key)
+		if counter.n > maxUploadSize {
+			return nil, models.ErrDocumentTooLarge
+		}
+		return nil, errors.New("document size does not match uploaded content")
+	}
+
+	document := &models.Document{
+		RequestId:  requestId,
+		Name:       name,
+		MimeType:   mimeType,
+		Size:       counter.n,
+		Checksum:   hex.EncodeToString(hash.Sum(nil)),
+		StorageKey: key,
+		UploadedBy: actor,
+	}
+	if err := s.documentRepository.Create(ctx, document); err != nil {
+		s.removeBlob(key)
+		return nil, err
+	}
+
+	return document, nil
+}
+
+func (s *documentService) GetByRequest(c context.Context, requestId int64) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(requestId), &req); err != nil {
+		return nil, err
+	}
+
+	documents := []models.Document{}
+	if err := s.documentRepository.GetByRequest(ctx, requestId, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// Open возвращает метаданные и поток содержимого документа. Поток должен быть закрыт вызывающим
+func (s *documentService) Open(c context.Context, requestId int64, id int64) (*models.Document, io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	document := &models.Document{}
+	if err := s.documentRepository.GetById(ctx, requestId, id, document); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, models.ErrDocumentNotFound
+		}
+		return nil, nil, err
+	}
+
+	// Чтение содержимого продолжается после выхода из метода, поэтому используем контекст запроса
+	content, err := s.store.Get(c, document.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, models.ErrDocumentNotFound
+		}
+		return nil, nil, err
+	}
+
+	return document, content, nil
+}
+
+func (s *documentService) Delete(c context.Context, requestId int64, id int64, actor models.Actor) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var document models.Document
+	if err := s.documentRepository.GetById(ctx, requestId, id, &document); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrDocumentNotFound
+		}
+		return err
+	}
+
+	// клиент может удалить только загруженный им документ
+	if !actor.IsEmployee() && document.UploadedBy != actor {
+		return models.ErrForbidden
+	}
+
+	if err := s.documentRepository.Delete(ctx, requestId, id); err != nil {
+		return err
+	}
+
+	s.removeBlob(document.StorageKey)
+	return nil
+}
+
+// removeBlob удаляет содержимое документа. Ошибка только логируется: запись в базе уже не ссылается на файл
+func (s *documentService) removeBlob(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.contextTimeout)
+	defer cancel()
+
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete blob %s: %v", key, err)
+	}
+}
+
+func detectMimeType(head []byte, name string) string {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	if detected == "application/zip" || detected == "application/octet-stream" {
+		if byExtension, ok := officeMimeTypes[strings.ToLower(filepath.Ext(name))]; ok {
+			return byExtension
+		}
+	}
+	return detected
+}
+
+func documentKey(requestId int64) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("requests/%d/%s", requestId, hex.EncodeToString(random)), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
+	"my_documents_south_backend/internal/models"
+	"net/url"
+	"time"
+)
+
+type EmailVerificationService struct {
+	emailVerificationRepository models.EmailVerificationRepository
+	userRepository              models.UserRepository
+	employeeRepository          models.EmployeeRepository
+	mailer                      mailer.Mailer
+	config                      config.EmailVerification
+	contextTimeout              time.Duration
+}
+
+func NewEmailVerificationService(
+	emailVerificationRepository models.EmailVerificationRepository,
+	userRepository models.UserRepository,
+	employeeRepository models.EmployeeRepository,
+	mailer mailer.Mailer,
+	config config.EmailVerification,
+	contextTimeout time.Duration,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		emailVerificationRepository: emailVerificationRepository,
+		userRepository:              userRepository,
+		employeeRepository:          employeeRepository,
+		mailer:                      mailer,
+		config:                      config,
+		contextTimeout:              contextTimeout,
+	}
+}
+
+// Send отправляет письмо со ссылкой подтверждения на почту email нового аккаунта subject
+func (s *EmailVerificationService) Send(c context.Context, subject models.Actor, email string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.send(ctx, subject, email)
+}
+
+// Resend повторно отправляет письмо подтверждения. Клиент указывает телефон, сотрудник — почту.
+// Результат не зависит от того, найден ли аккаунт, чтобы по ответу нельзя было проверить его существование
+func (s *EmailVerificationService) Resend(c context.Context, phone string, email string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	account, err := findAccount(ctx, s.userRepository, s.employeeRepository, phone, email)
+	if err != nil {
+		log.Printf("email verification: account not found: %v", err)
+		return nil
+	}
+	if account.email == "" || account.emailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.send(ctx, account.subject, account.email)
+}
+
+// Verify подтверждает почту по токену из письма
+func (s *EmailVerificationService) Verify(c context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if token == "" {
+		return models.ErrInvalidVerificationToken
+	}
+
+	_, err := s.emailVerificationRepository.Verify(ctx, hashToken(token))
+	return err
+}
+
+// CheckLogin запрещает вход с неподтверждённой почтой, если это требует политика
+func (s *EmailVerificationService) CheckLogin(emailVerifiedAt *time.Time) error {
+	if s.config.Policy == config.EmailPolicyLogin && emailVerifiedAt == nil {
+		return models.ErrEmailNotVerified
+	}
+	return nil
+}
+
+// CheckRequest запрещает создание заявок с неподтверждённой почтой, если это требует политика
+func (s *EmailVerificationService) CheckRequest(c context.Context, actor models.Actor) error {
+	if s.config.Policy == config.EmailPolicyNone {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	verified, err := s.emailVerificationRepository.IsVerified(ctx, actor)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return models.ErrEmailNotVerified
+	}
+	return nil
+}
+
+func (s *EmailVerificationService) send(ctx context.Context, subject models.Actor, email string) error {
+	count, err := s.emailVerificationRepository.CountSince(ctx, subject, time.Now().Add(-s.config.Window))
+	if err != nil {
+		return err
+	}
+	if count >= s.config.MaxRequests {
+		log.Printf("email verification: rate limit exceeded for %s %d", subject.Type, subject.Id)
+		return nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{
+		Subject:   subject,
+		Email:     email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}
+	if err := s.emailVerificationRepository.Create(ctx, verification); err != nil {
+		return err
+	}
+
+	message, err := s.message(email, token)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.contextTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, message); err != nil {
+			log.Printf("email verification: failed to send mail to %s %d: %v", subject.Type, subject.Id, err)
+		}
+	}()
+	return nil
+}
+
+func (s *EmailVerificationService) message(to string, token string) (mailer.Message, error) {
+	link, err := url.Parse(s.config.URL)
+	if err != nil {
+		return mailer.Message{}, errors.New("invalid email verification url")
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return mailer.Message{
+		To:      to,
+		Subject: "Подтверждение почты",
+		Body: fmt.Sprintf(
+			"Для подтверждения почты перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует %d ч.\n"+
+				"Если вы не регистрировались, проигнорируйте это письмо.\n",
+			link.String(),
+			int(s.config.TTL.Hours()),
+		),
+	}, nil
+}
@@ -7,58 +7,50 @@ import (
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/utils/password"
 	"regexp"
+	"strings"
 	"time"
-	"unicode"
+	"unicode/utf8"
 )
 
 type employeeService struct {
 	employeeRepository models.EmployeeRepository
-	roleRepository     models.RoleRepository
+	roleService        models.RoleService
+	sessionRepository  models.SessionRepository
 	contextTimeout     time.Duration
 }
 
 func NewEmployeeService(
 	employeeRepository models.EmployeeRepository,
-	roleRepository models.RoleRepository,
+	roleService models.RoleService,
+	sessionRepository models.SessionRepository,
 	contextTimeout time.Duration,
 ) models.EmployeeService {
 	return &employeeService{
 		employeeRepository: employeeRepository,
-		roleRepository:     roleRepository,
+		roleService:        roleService,
+		sessionRepository:  sessionRepository,
 		contextTimeout:     contextTimeout,
 	}
 }
 
+// emailPattern допустимый формат почты сотрудника
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
 func (s *employeeService) Create(c context.Context, employee *models.Employee) error {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
-	hasLetter := false
-	hasDigit := false
-
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-	if !emailRegex.MatchString(employee.Email) {
+	// почта хранится в нижнем регистре, как после validate
+	employee.Email = strings.ToLower(strings.TrimSpace(employee.Email))
+	if !emailPattern.MatchString(employee.Email) {
 		return errors.New("invalid email format")
 	}
 
-	if len(employee.Password) < 6 {
-		return errors.New("invalid password: must contain at least 6 characters")
-	}
-
-	for _, ch := range employee.Password {
-		if unicode.IsLetter(ch) {
-			hasLetter = true
-		}
-		if unicode.IsDigit(ch) {
-			hasDigit = true
-		}
-	}
-	if !hasLetter || !hasDigit {
-		return errors.New("invalid password: must contain at least one letter and one digit")
+	if err := password.Validate(employee.Password); err != nil {
+		return err
 	}
 
-	var role models.Role
-	err := s.roleRepository.GetById(ctx, employee.RoleId, &role)
+	_, err := s.roleService.GetById(ctx, employee.RoleId)
 	if err != nil {
 		return fmt.Errorf("failed to check default role: %w", err)
 	}
@@ -75,23 +67,163 @@ func (s *employeeService) Create(c context.Context, employee *models.Employee) e
 	return nil
 }
 
-func (s *employeeService) Get(c context.Context) *[]models.Employee { return nil }
+func (s *employeeService) Get(c context.Context) *[]models.Employee {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var employees []models.Employee
+	if err := s.employeeRepository.Get(ctx, &employees); err != nil {
+		return nil
+	}
+	return &employees
+}
 
 func (s *employeeService) GetById(c context.Context, id int) (*models.Employee, error) {
-	return nil, nil
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var employee models.Employee
+	if err := s.employeeRepository.GetById(ctx, id, &employee); err != nil {
+		return nil, err
+	}
+	return &employee, nil
 }
 
+// Update сохраняет имя, почту и роль сотрудника id после проверки. Пароль и активность не меняются
 func (s *employeeService) Update(c context.Context, id int, employee *models.Employee) error {
-	// TODO update employee service
-	// DONT TOUCH
-	return nil
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	employee.Id = int64(id)
+	if err := s.validate(ctx, employee); err != nil {
+		return err
+	}
+	return s.employeeRepository.Update(ctx, employee)
 }
 
-func (s *employeeService) Delete(c context.Context, id int) error {
+func (s *employeeService) Patch(c context.Context, actor *models.Principal, id int64, update models.EmployeeUpdate) (*models.Employee, error) {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
-	return s.employeeRepository.Delete(ctx, id)
+	var employee models.Employee
+	if err := s.employeeRepository.GetById(ctx, int(id), &employee); err != nil {
+		return nil, err
+	}
+	if err := s.checkManage(ctx, actor, &employee); err != nil {
+		return nil, err
+	}
+	roleId := employee.RoleId
+
+	if update.Name != nil {
+		employee.Name = *update.Name
+	}
+	if update.LastName != nil {
+		employee.LastName = *update.LastName
+	}
+	if update.MiddleName != nil {
+		employee.MiddleName = *update.MiddleName
+	}
+	if update.Email != nil {
+		employee.Email = *update.Email
+	}
+	if update.RoleId != nil {
+		employee.RoleId = *update.RoleId
+	}
+
+	if err := s.validate(ctx, &employee); err != nil {
+		return nil, err
+	}
+	if employee.RoleId != roleId {
+		if err := checkRoleGrant(ctx, s.roleService, actor, employee.RoleId); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.employeeRepository.Update(ctx, &employee); err != nil {
+		return nil, err
+	}
+
+	if employee.RoleId != roleId {
+		// права роли зашиты в токены, поэтому после смены роли сотрудник должен войти заново
+		if err := s.sessionRepository.RevokeSubject(ctx, models.Actor{Type: models.ActorEmployee, Id: id}); err != nil {
+			return nil, err
+		}
+	}
+	return &employee, nil
+}
+
+func (s *employeeService) ChangePassword(c context.Context, principal *models.Principal, current string, next string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	hash, err := s.employeeRepository.GetPassword(ctx, principal.Id)
+	if err != nil {
+		return err
+	}
+	if err := password.Compare(hash, current); err != nil {
+		return models.ErrWrongPassword
+	}
+
+	if err := password.Validate(next); err != nil {
+		return err
+	}
+	hash, err = password.Encrypt(next)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := s.employeeRepository.SetPassword(ctx, principal.Id, hash); err != nil {
+		return err
+	}
+	return s.sessionRepository.RevokeOthers(ctx, principal.Actor(), principal.SessionId)
+}
+
+func (s *employeeService) SetActive(c context.Context, actor *models.Principal, id int64, active bool) (*models.Employee, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if !active && actor.Id == id {
+		return nil, fmt.Errorf("%w: cannot deactivate yourself", models.ErrInvalidEmployee)
+	}
+
+	var employee models.Employee
+	if err := s.employeeRepository.GetById(ctx, int(id), &employee); err != nil {
+		return nil, err
+	}
+	if err := s.checkManage(ctx, actor, &employee); err != nil {
+		return nil, err
+	}
+
+	if err := s.employeeRepository.SetActive(ctx, id, active); err != nil {
+		return nil, err
+	}
+	employee.Active = active
+
+	if !active {
+		// access токены проверяются по активной сессии, поэтому перестают действовать сразу
+		if err := s.sessionRepository.RevokeSubject(ctx, models.Actor{Type: models.ActorEmployee, Id: id}); err != nil {
+			return nil, err
+		}
+	}
+	return &employee, nil
+}
+
+func (s *employeeService) Delete(c context.Context, actor *models.Principal, id int64) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if actor.Id == id {
+		return fmt.Errorf("%w: cannot delete yourself", models.ErrInvalidEmployee)
+	}
+
+	var employee models.Employee
+	if err := s.employeeRepository.GetById(ctx, int(id), &employee); err != nil {
+		return err
+	}
+	if err := s.checkManage(ctx, actor, &employee); err != nil {
+		return err
+	}
+
+	return s.employeeRepository.Delete(ctx, int(id))
 }
 
 func (s *employeeService) AddService(ctx context.Context, employeeID int64, serviceID int) error {
@@ -115,9 +247,42 @@ func (s *employeeService) GetByIdWithServices(ctx context.Context, id int64) (*m
 	return s.employeeRepository.GetByIdWithServices(ctx, id)
 }
 
-func (s *employeeService) GetAllWithServices(ctx context.Context) ([]models.Employee, error) {
+func (s *employeeService) GetAllWithServices(ctx context.Context, filter models.EmployeeFilter) ([]models.Employee, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
 	defer cancel()
 
-	return s.employeeRepository.GetAllWithServices(ctx)
+	return s.employeeRepository.GetAllWithServices(ctx, filter)
+}
+
+// validate нормализует и проверяет имя, почту и роль сотрудника
+func (s *employeeService) validate(ctx context.Context, employee *models.Employee) error {
+	employee.Name = strings.TrimSpace(employee.Name)
+	employee.LastName = strings.TrimSpace(employee.LastName)
+	employee.MiddleName = strings.TrimSpace(employee.MiddleName)
+	if employee.Name == "" || employee.LastName == "" {
+		return fmt.Errorf("%w: name and last_name are required", models.ErrInvalidEmployee)
+	}
+	if utf8.RuneCountInString(employee.Name) > 100 || utf8.RuneCountInString(employee.LastName) > 100 ||
+		utf8.RuneCountInString(employee.MiddleName) > 100 {
+		return fmt.Errorf("%w: names must not exceed 100 characters", models.ErrInvalidEmployee)
+	}
+
+	employee.Email = strings.ToLower(strings.TrimSpace(employee.Email))
+	if !emailPattern.MatchString(employee.Email) || len(employee.Email) > 255 {
+		return fmt.Errorf("%w: invalid email format", models.ErrInvalidEmployee)
+	}
+
+	if _, err := s.roleService.GetById(ctx, employee.RoleId); err != nil {
+		return fmt.Errorf("%w: role not found", models.ErrInvalidEmployee)
+	}
+	return nil
+}
+
+// checkManage разрешает изменять сотрудника, только если actor может выдать его роль.
+// Иначе через смену почты можно было бы завладеть аккаунтом с большими правами
+func (s *employeeService) checkManage(ctx context.Context, actor *models.Principal, employee *models.Employee) error {
+	if employee.RoleId == 0 {
+		return nil
+	}
+	return checkRoleGrant(ctx, s.roleService, actor, employee.RoleId)
 }
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/utils/password"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxInviteTTL наибольший срок действия приглашения, который можно указать при создании
+const maxInviteTTL = 30 * 24 * time.Hour
+
+type InviteService struct {
+	inviteRepository models.InviteRepository
+	roleService      models.RoleService
+	mailer           mailer.Mailer
+	config           config.Invite
+	contextTimeout   time.Duration
+}
+
+func NewInviteService(
+	inviteRepository models.InviteRepository,
+	roleService models.RoleService,
+	mailer mailer.Mailer,
+	config config.Invite,
+	contextTimeout time.Duration,
+) *InviteService {
+	return &InviteService{
+		inviteRepository: inviteRepository,
+		roleService:      roleService,
+		mailer:           mailer,
+		config:           config,
+		contextTimeout:   contextTimeout,
+	}
+}
+
+// Create приглашает сотрудника и отправляет ссылку на почту. Сотрудник может пригласить только в роль,
+// все права которой есть у его собственной роли, а в суперроль — только сотрудник с суперролью
+func (s *InviteService) Create(c context.Context, inviter *models.Principal, invite *models.EmployeeInvite) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	invite.Email = strings.ToLower(strings.TrimSpace(invite.Email))
+	if !emailPattern.MatchString(invite.Email) {
+		return fmt.Errorf("%w: invalid email format", models.ErrInvalidInvite)
+	}
+
+	now := time.Now()
+	if invite.ExpiresAt.IsZero() {
+		invite.ExpiresAt = now.Add(s.config.TTL)
+	}
+	if !invite.ExpiresAt.After(now) || invite.ExpiresAt.After(now.Add(maxInviteTTL)) {
+		return fmt.Errorf("%w: expires_at must be in the future and within %d days", models.ErrInvalidInvite, int(maxInviteTTL.Hours()/24))
+	}
+
+	if err := s.checkRole(ctx, inviter, invite.RoleId); err != nil {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	invite.TokenHash = hashToken(token)
+	invite.InvitedBy = &inviter.Id
+
+	if err := s.inviteRepository.Create(ctx, invite); err != nil {
+		return err
+	}
+
+	message, err := s.message(invite, token)
+	if err != nil {
+		return err
+	}
+	// без письма приглашение бесполезно: повторное приглашение на ту же почту отзовёт это
+	if err := s.mailer.Send(ctx, message); err != nil {
+		return fmt.Errorf("failed to send invite: %w", err)
+	}
+	return nil
+}
+
+// GetPending действующие приглашения
+func (s *InviteService) GetPending(c context.Context) ([]models.EmployeeInvite, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	invites := []models.EmployeeInvite{}
+	if err := s.inviteRepository.GetPending(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// GetByToken приглашение для страницы принятия
+func (s *InviteService) GetByToken(c context.Context, token string) (*models.EmployeeInvite, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var invite models.EmployeeInvite
+	if err := s.inviteRepository.GetByToken(ctx, hashToken(token), &invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (s *InviteService) Revoke(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.inviteRepository.Revoke(ctx, id)
+}
+
+// Accept создаёт сотрудника по приглашению. Незаполненные имена берутся из приглашения
+func (s *InviteService) Accept(c context.Context, token string, employee *models.Employee) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var invite models.EmployeeInvite
+	if err := s.inviteRepository.GetByToken(ctx, hashToken(token), &invite); err != nil {
+		return err
+	}
+
+	employee.Name = valueOr(employee.Name, invite.Name)
+	employee.LastName = valueOr(employee.LastName, invite.LastName)
+	employee.MiddleName = valueOr(employee.MiddleName, invite.MiddleName)
+	if employee.Name == "" || employee.LastName == "" {
+		return fmt.Errorf("%w: name and last_name are required", models.ErrInvalidInvite)
+	}
+
+	if err := password.Validate(employee.Password); err != nil {
+		return err
+	}
+
+	hash, err := password.Encrypt(employee.Password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	employee.Password = hash
+
+	return s.inviteRepository.Accept(ctx, hashToken(token), employee)
+}
+
+// checkRole запрещает приглашать в роль с правами, которых нет у приглашающего
+func (s *InviteService) checkRole(ctx context.Context, inviter *models.Principal, roleId int) error {
+	if roleId < 1 {
+		return fmt.Errorf("%w: role_id is required", models.ErrInvalidInvite)
+	}
+	if _, err := s.roleService.GetById(ctx, roleId); err != nil {
+		return fmt.Errorf("%w: role not found", models.ErrInvalidInvite)
+	}
+	return checkRoleGrant(ctx, s.roleService, inviter, roleId)
+}
+
+func (s *InviteService) message(invite *models.EmployeeInvite, token string) (mailer.Message, error) {
+	link, err := url.Parse(s.config.URL)
+	if err != nil {
+		return mailer.Message{}, errors.New("invalid invite url")
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return mailer.Message{
+		To:      invite.Email,
+		Subject: "Приглашение в команду",
+		Body: fmt.Sprintf(
+			"Вас пригласили присоединиться к команде сотрудников. Чтобы создать аккаунт, перейдите по ссылке:\n%s\n\n"+
+				"Приглашение действует до %s.\n"+
+				"Если вы не ждали приглашения, проигнорируйте это письмо.\n",
+			link.String(),
+			invite.ExpiresAt.Format("02.01.2006 15:04 MST"),
+		),
+	}, nil
+}
+
+func valueOr(value string, fallback *string) string {
+	value = strings.TrimSpace(value)
+	if value == "" && fallback != nil {
+		return *fallback
+	}
+	return value
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLoginKeyLength ограничение длины ключа аккаунта, как у адреса почты
+const maxLoginKeyLength = 254
+
+// LoginGuard ограничивает неудачные попытки входа по паролю с одного IP и в один аккаунт.
+// Проверка выполняется до сравнения паролей, чтобы заблокированные запросы не тратили время на bcrypt
+type LoginGuard struct {
+	store           models.LoginAttemptStore
+	auditRepository models.AuditRepository
+	config          config.Lockout
+}
+
+func NewLoginGuard(store models.LoginAttemptStore, auditRepository models.AuditRepository, config config.Lockout) *LoginGuard {
+	return &LoginGuard{store: store, auditRepository: auditRepository, config: config}
+}
+
+// Check возвращает models.RateLimitError, если вход с ip или в account заблокирован
+func (g *LoginGuard) Check(ctx context.Context, ip string, account string) error {
+	now := time.Now()
+
+	var retryAfter time.Duration
+	for _, key := range []string{ipKey(ip), accountKey(account)} {
+		var lockout models.LoginLockout
+		if err := g.store.GetLockout(ctx, key, &lockout); err != nil {
+			return err
+		}
+		if wait := lockout.LockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &models.RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Fail учитывает неудачную попытку входа и блокирует IP или аккаунт, если попыток стало слишком много
+func (g *LoginGuard) Fail(ctx context.Context, ip string, account string) error {
+	if err := g.fail(ctx, ipKey(ip), g.config.IPMaxAttempts, ip); err != nil {
+		return err
+	}
+	return g.fail(ctx, accountKey(account), g.config.AccountMaxAttempts, ip)
+}
+
+// Succeed сбрасывает попытки и уровень блокировки аккаунта после успешного входа.
+// Счётчик IP не сбрасывается, иначе его можно обнулять входом в свой аккаунт
+func (g *LoginGuard) Succeed(ctx context.Context, account string) error {
+	return g.store.Reset(ctx, accountKey(account))
+}
+
+func (g *LoginGuard) fail(ctx context.Context, key string, maxAttempts int, ip string) error {
+	now := time.Now()
+
+	attempts, err := g.store.AddFailure(ctx, key, now.Add(-g.config.Window))
+	if err != nil {
+		return err
+	}
+	if attempts < maxAttempts {
+		return nil
+	}
+
+	var previous models.LoginLockout
+	if err := g.store.GetLockout(ctx, key, &previous); err != nil {
+		return err
+	}
+
+	// уровень растёт только для блокировок подряд: после MaxDuration без блокировок отсчёт начинается заново
+	level := 1
+	if !previous.LockedUntil.IsZero() && now.Sub(previous.LockedUntil) < g.config.MaxDuration {
+		level = previous.Level + 1
+	}
+
+	until := now.Add(g.lockoutDuration(level))
+	if err := g.store.Lock(ctx, key, until, level); err != nil {
+		return err
+	}
+
+	log.Printf("auth: sign-in locked for %s until %s after %d failed attempts", key, until.Format(time.RFC3339), attempts)
+
+	details, err := json.Marshal(map[string]any{
+		"key":          key,
+		"attempts":     attempts,
+		"level":        level,
+		"locked_until": until,
+	})
+	if err != nil {
+		return err
+	}
+	return g.auditRepository.Create(ctx, &models.AuditEvent{Type: models.AuditLoginLockout, Ip: ip, Details: details})
+}
+
+// lockoutDuration Duration * 2^(level-1), но не больше MaxDuration
+func (g *LoginGuard) lockoutDuration(level int) time.Duration {
+	duration := g.config.Duration
+	for i := 1; i < level && duration < g.config.MaxDuration; i++ {
+		duration *= 2
+	}
+	return min(duration, g.config.MaxDuration)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// accountKey ключ аккаунта, например employee:name@example.com или user:79998887766
+func accountKey(account string) string {
+	account = strings.ToLower(strings.TrimSpace(account))
+	if utf8.RuneCountInString(account) > maxLoginKeyLength {
+		account = string([]rune(account)[:maxLoginKeyLength])
+	}
+	return account
+}
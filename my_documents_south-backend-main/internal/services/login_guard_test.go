@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/memory"
+	"strings"
+	"testing"
+	"time"
+)
+
+// auditRecorder сохраняет события аудита в памяти
+type auditRecorder struct {
+	events []models.AuditEvent
+}
+
+func (r *auditRecorder) Create(_ context.Context, event *models.AuditEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+var testLockout = config.Lockout{
+	Window:             15 * time.Minute,
+	IPMaxAttempts:      10,
+	AccountMaxAttempts: 3,
+	Duration:           time.Minute,
+	MaxDuration:        time.Hour,
+}
+
+func newTestLoginGuard(cfg config.Lockout) (*LoginGuard, *auditRecorder) {
+	audit := &auditRecorder{}
+	return NewLoginGuard(memory.NewLoginAttemptStore(cfg.Window, cfg.MaxDuration), audit, cfg), audit
+}
+
+func TestLoginGuardLockoutDuration(t *testing.T) {
+	guard, _ := newTestLoginGuard(testLockout)
+
+	tests := []struct {
+		level int
+		want  time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := guard.lockoutDuration(tt.level); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLoginGuardLocksAccount(t *testing.T) {
+	ctx := context.Background()
+	guard, audit := newTestLoginGuard(testLockout)
+	account := "employee:name@example.com"
+
+	for i := 1; i < testLockout.AccountMaxAttempts; i++ {
+		if err := guard.Fail(ctx, "10.0.0.1", account); err != nil {
+			t.Fatal(err)
+		}
+		if err := guard.Check(ctx, "10.0.0.1", account); err != nil {
+			t.Fatalf("locked after %d attempts: %v", i, err)
+		}
+	}
+	if err := guard.Fail(ctx, "10.0.0.1", account); err != nil {
+		t.Fatal(err)
+	}
+
+	// блокировка аккаунта действует с любого IP и без учёта регистра почты
+	err := guard.Check(ctx, "10.0.0.2", strings.ToUpper(account))
+	var rateLimit *models.RateLimitError
+	if !errors.As(err, &rateLimit) {
+		t.Fatalf("Check() error = %v, want RateLimitError", err)
+	}
+	if rateLimit.RetryAfter <= 0 || rateLimit.RetryAfter > testLockout.Duration {
+		t.Errorf("RetryAfter = %s, want (0, %s]", rateLimit.RetryAfter, testLockout.Duration)
+	}
+	if len(audit.events) != 1 || audit.events[0].Type != models.AuditLoginLockout {
+		t.Errorf("audit events = %+v, want one lockout event", audit.events)
+	}
+
+	if err := guard.Check(ctx, "10.0.0.2", "employee:other@example.com"); err != nil {
+		t.Errorf("other account is locked: %v", err)
+	}
+}
+
+func TestLoginGuardRepeatedLockoutDoubles(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newTestLoginGuard(testLockout)
+	account := "user:79998887766"
+
+	for lockout, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		for i := 0; i < testLockout.AccountMaxAttempts; i++ {
+			if err := guard.Fail(ctx, "10.0.0.1", account); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var rateLimit *models.RateLimitError
+		if err := guard.Check(ctx, "10.0.0.1", account); !errors.As(err, &rateLimit) {
+			t.Fatalf("lockout %d: Check() error = %v, want RateLimitError", lockout+1, err)
+		}
+		if rateLimit.RetryAfter <= want/2 || rateLimit.RetryAfter > want {
+			t.Errorf("lockout %d: RetryAfter = %s, want about %s", lockout+1, rateLimit.RetryAfter, want)
+		}
+	}
+}
+
+func TestLoginGuardSucceedResetsOnlyAccount(t *testing.T) {
+	ctx := context.Background()
+	cfg := testLockout
+	cfg.IPMaxAttempts = 4
+	guard, _ := newTestLoginGuard(cfg)
+	account := "employee:name@example.com"
+
+	for i := 0; i < cfg.AccountMaxAttempts; i++ {
+		if err := guard.Fail(ctx, "10.0.0.1", account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := guard.Succeed(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Check(ctx, "10.0.0.2", account); err != nil {
+		t.Errorf("account is still locked after success: %v", err)
+	}
+
+	// счётчик IP не сбрасывается успешным входом
+	if err := guard.Fail(ctx, "10.0.0.1", "employee:other@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Check(ctx, "10.0.0.1", "employee:third@example.com"); !errors.Is(err, models.ErrTooManyRequests) {
+		t.Errorf("Check() error = %v, want IP lockout", err)
+	}
+}
+
+func TestAccountKey(t *testing.T) {
+	tests := []struct {
+		account string
+		want    string
+	}{
+		{"employee:Name@Example.com", "employee:name@example.com"},
+		{"  user:79998887766 ", "user:79998887766"},
+		{strings.Repeat("я", maxLoginKeyLength+10), strings.Repeat("я", maxLoginKeyLength)},
+	}
+
+	for _, tt := range tests {
+		if got := accountKey(tt.account); got != tt.want {
+			t.Errorf("accountKey(%q) = %q, want %q", tt.account, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/utils/totp"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew допустимое расхождение часов в шагах TOTP
+	totpSkew = 1
+)
+
+type MFAService struct {
+	mfaRepository      models.MFARepository
+	employeeRepository models.EmployeeRepository
+	roleRepository     models.RoleRepository
+	config             config.MFA
+	contextTimeout     time.Duration
+}
+
+func NewMFAService(
+	mfaRepository models.MFARepository,
+	employeeRepository models.EmployeeRepository,
+	roleRepository models.RoleRepository,
+	config config.MFA,
+	contextTimeout time.Duration,
+) *MFAService {
+	return &MFAService{
+		mfaRepository:      mfaRepository,
+		employeeRepository: employeeRepository,
+		roleRepository:     roleRepository,
+		config:             config,
+		contextTimeout:     contextTimeout,
+	}
+}
+
+// Status состояние двухфакторной аутентификации сотрудника
+func (s *MFAService) Status(c context.Context, employeeId int64, roleId int) (*models.MFAStatus, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	enabled, required, err := s.check(ctx, employeeId, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.MFAStatus{Enabled: enabled, Required: required}
+	if enabled {
+		if status.RecoveryCodesLeft, err = s.mfaRepository.CountRecoveryCodes(ctx, employeeId); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll создаёт новый секрет TOTP. Подключение завершается вводом кода из приложения через Confirm
+func (s *MFAService) Enroll(c context.Context, employeeId int64) (*models.TOTPEnrollment, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.enroll(ctx, employeeId)
+}
+
+// Confirm завершает подключение TOTP и возвращает коды восстановления. Коды показываются только один раз
+func (s *MFAService) Confirm(c context.Context, employeeId int64, code string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var secret models.EmployeeTOTP
+	if err := s.mfaRepository.GetTOTP(ctx, employeeId, &secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrMFAEnrollmentRequired
+		}
+		return nil, err
+	}
+	if secret.ConfirmedAt != nil {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	return s.confirm(ctx, &secret, code)
+}
+
+// Disable отключает TOTP. Требует действующий код и недоступно, если второй фактор обязателен для роли
+func (s *MFAService) Disable(c context.Context, employeeId int64, roleId int, code string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	required, err := s.required(ctx, roleId)
+	if err != nil {
+		return err
+	}
+	if required {
+		return models.ErrMFARequired
+	}
+
+	if err := s.verifyCode(ctx, employeeId, code); err != nil {
+		return err
+	}
+	return s.mfaRepository.DeleteTOTP(ctx, employeeId)
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми. Требует действующий код
+func (s *MFAService) RegenerateRecoveryCodes(c context.Context, employeeId int64, code string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if err := s.verifyCode(ctx, employeeId, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepository.ReplaceRecoveryCodes(ctx, employeeId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// StartChallenge проверяет, нужен ли сотруднику второй фактор, и если нужен, начинает вход со вторым фактором
+func (s *MFAService) StartChallenge(ctx context.Context, employee *models.Employee) (*models.MFAChallengeResponse, error) {
+	enabled, required, err := s.check(ctx, employee.Id, employee.RoleId)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled && !required {
+		return nil, nil
+	}
+
+	// каждый вход даёт новые попытки ввода кода, поэтому их количество ограничено
+	now := time.Now()
+	stats, err := s.mfaRepository.ChallengeStats(ctx, employee.Id, now.Add(-s.config.ChallengeWindow))
+	if err != nil {
+		return nil, err
+	}
+	if stats.Count >= s.config.MaxChallenges && stats.FirstAt != nil {
+		return nil, &models.RateLimitError{RetryAfter: stats.FirstAt.Add(s.config.ChallengeWindow).Sub(now)}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.MFAChallenge{
+		EmployeeId: employee.Id,
+		TokenHash:  hashToken(token),
+		ExpiresAt:  now.Add(s.config.ChallengeTTL),
+	}
+	if err := s.mfaRepository.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &models.MFAChallengeResponse{
+		MfaRequired:        true,
+		Token:              token,
+		ExpiresAt:          challenge.ExpiresAt,
+		EnrollmentRequired: !enabled,
+	}, nil
+}
+
+// ChallengeEmployee возвращает сотрудника, который начал действующий вход token
+func (s *MFAService) ChallengeEmployee(c context.Context, token string) (int64, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var challenge models.MFAChallenge
+	if err := s.mfaRepository.GetChallenge(ctx, hashToken(token), &challenge); err != nil {
+		return 0, err
+	}
+	return challenge.EmployeeId, nil
+}
+
+// EnrollChallenge подключает TOTP во время входа, если второй фактор обязателен, но ещё не подключен
+func (s *MFAService) EnrollChallenge(c context.Context, token string) (*models.TOTPEnrollment, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var challenge models.MFAChallenge
+	if err := s.mfaRepository.GetChallenge(ctx, hashToken(token), &challenge); err != nil {
+		return nil, err
+	}
+
+	return s.enroll(ctx, challenge.EmployeeId)
+}
+
+// VerifyChallenge проверяет второй фактор входа token. Если подключение TOTP не завершено,
+// код завершает его, и возвращаются новые коды восстановления
+func (s *MFAService) VerifyChallenge(c context.Context, token string, code string) (int64, []string, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var challenge models.MFAChallenge
+	if err := s.mfaRepository.AttemptChallenge(ctx, hashToken(token), s.config.MaxAttempts, &challenge); err != nil {
+		return 0, nil, err
+	}
+
+	var secret models.EmployeeTOTP
+	if err := s.mfaRepository.GetTOTP(ctx, challenge.EmployeeId, &secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, models.ErrMFAEnrollmentRequired
+		}
+		return 0, nil, err
+	}
+
+	var recoveryCodes []string
+	if secret.ConfirmedAt == nil {
+		codes, err := s.confirm(ctx, &secret, code)
+		if err != nil {
+			return 0, nil, err
+		}
+		recoveryCodes = codes
+	} else if err := s.verifyCode(ctx, challenge.EmployeeId, code); err != nil {
+		return 0, nil, err
+	}
+
+	if err := s.mfaRepository.CompleteChallenge(ctx, challenge.Id); err != nil {
+		return 0, nil, err
+	}
+	return challenge.EmployeeId, recoveryCodes, nil
+}
+
+func (s *MFAService) enroll(ctx context.Context, employeeId int64) (*models.TOTPEnrollment, error) {
+	employee, err := s.employeeRepository.GetByIdWithServices(ctx, employeeId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepository.SavePendingTOTP(ctx, employeeId, secret); err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(s.config.Issuer, employee.Email, secret)
+	qr, err := totp.QRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{Secret: secret, URI: uri, QRCode: qr}, nil
+}
+
+func (s *MFAService) confirm(ctx context.Context, secret *models.EmployeeTOTP, code string) ([]string, error) {
+	step, ok := totp.Validate(secret.Secret, normalizeCode(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, models.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepository.ConfirmTOTP(ctx, secret.EmployeeId, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyCode принимает код TOTP или код восстановления подключенного TOTP
+func (s *MFAService) verifyCode(ctx context.Context, employeeId int64, code string) error {
+	var secret models.EmployeeTOTP
+	if err := s.mfaRepository.GetTOTP(ctx, employeeId, &secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrMFANotEnabled
+		}
+		return err
+	}
+	if secret.ConfirmedAt == nil {
+		return models.ErrMFANotEnabled
+	}
+
+	code = normalizeCode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(secret.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return models.ErrInvalidMFACode
+		}
+		return s.mfaRepository.UseTOTPStep(ctx, employeeId, step)
+	}
+
+	return s.mfaRepository.UseRecoveryCode(ctx, employeeId, hashToken(code))
+}
+
+// check возвращает, подключен ли TOTP у сотрудника и обязателен ли второй фактор для его роли
+func (s *MFAService) check(ctx context.Context, employeeId int64, roleId int) (bool, bool, error) {
+	required, err := s.required(ctx, roleId)
+	if err != nil {
+		return false, false, err
+	}
+
+	var secret models.EmployeeTOTP
+	err = s.mfaRepository.GetTOTP(ctx, employeeId, &secret)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, false, err
+	}
+	return err == nil && secret.ConfirmedAt != nil, required, nil
+}
+
+func (s *MFAService) required(ctx context.Context, roleId int) (bool, error) {
+	var role models.Role
+	if err := s.roleRepository.GetById(ctx, roleId, &role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return role.MfaRequired, nil
+}
+
+// generateRecoveryCodes коды восстановления вида xxxxx-xxxxx и их хеши для хранения
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random := make([]byte, 7)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(random))[:10]
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode убирает пробелы и дефисы, которые пользователи вводят вместе с кодом
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/sms"
+	"time"
+
+	"github.com/dongri/phonenumber"
+)
+
+type OTPService struct {
+	otpRepository  models.OTPRepository
+	userRepository models.UserRepository
+	smsProvider    sms.SMSProvider
+	config         config.OTP
+	contextTimeout time.Duration
+}
+
+func NewOTPService(
+	otpRepository models.OTPRepository,
+	userRepository models.UserRepository,
+	smsProvider sms.SMSProvider,
+	config config.OTP,
+	contextTimeout time.Duration,
+) *OTPService {
+	return &OTPService{
+		otpRepository:  otpRepository,
+		userRepository: userRepository,
+		smsProvider:    smsProvider,
+		config:         config,
+		contextTimeout: contextTimeout,
+	}
+}
+
+// SendLogin отправляет код для входа клиенту с номером phone.
+// Если клиент не найден, код не отправляется, но ошибка не возвращается
+func (s *OTPService) SendLogin(c context.Context, phone string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	normalized := phonenumber.Parse(phone, "RU")
+	if normalized == "" {
+		return models.ErrInvalidPhone
+	}
+
+	var user models.User
+	if err := s.userRepository.GetByPhone(ctx, normalized, &user); err != nil {
+		return nil
+	}
+
+	return s.send(ctx, normalized, models.OTPLogin)
+}
+
+// SendVerification отправляет код подтверждения на телефон клиента userId
+func (s *OTPService) SendVerification(c context.Context, userId int64) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var user models.User
+	if err := s.userRepository.GetById(ctx, int(userId), &user); err != nil {
+		return err
+	}
+	if user.PhoneVerifiedAt != nil {
+		return models.ErrPhoneVerified
+	}
+
+	return s.send(ctx, user.Phone, models.OTPVerifyPhone)
+}
+
+// ConfirmPhone подтверждает телефон клиента userId кодом из SMS
+func (s *OTPService) ConfirmPhone(c context.Context, userId int64, code string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var user models.User
+	if err := s.userRepository.GetById(ctx, int(userId), &user); err != nil {
+		return err
+	}
+	if user.PhoneVerifiedAt != nil {
+		return models.ErrPhoneVerified
+	}
+
+	if err := s.verify(ctx, user.Phone, models.OTPVerifyPhone, code); err != nil {
+		return err
+	}
+	return s.userRepository.SetPhoneVerified(ctx, userId)
+}
+
+// Verify проверяет код, отправленный на номер phone
+func (s *OTPService) Verify(c context.Context, phone string, purpose models.OTPPurpose, code string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	normalized := phonenumber.Parse(phone, "RU")
+	if normalized == "" {
+		return models.ErrInvalidPhone
+	}
+	return s.verify(ctx, normalized, purpose, code)
+}
+
+func (s *OTPService) verify(ctx context.Context, phone string, purpose models.OTPPurpose, code string) error {
+	if len(code) != s.config.Length {
+		return models.ErrInvalidOTP
+	}
+	return s.otpRepository.Verify(ctx, phone, purpose, hashOTP(phone, purpose, code), s.config.MaxAttempts)
+}
+
+// send проверяет ограничения на отправку, сохраняет новый код и отправляет его по SMS.
+// Новый код заменяет ранее отправленные: проверяется только последний
+func (s *OTPService) send(ctx context.Context, phone string, purpose models.OTPPurpose) error {
+	now := time.Now()
+
+	stats, err := s.otpRepository.Stats(ctx, phone, purpose, now.Add(-s.config.Window))
+	if err != nil {
+		return err
+	}
+	if stats.LastAt != nil {
+		if wait := stats.LastAt.Add(s.config.Cooldown).Sub(now); wait > 0 {
+			return &models.RateLimitError{RetryAfter: wait}
+		}
+	}
+	if stats.Count >= s.config.MaxRequests && stats.FirstAt != nil {
+		return &models.RateLimitError{RetryAfter: stats.FirstAt.Add(s.config.Window).Sub(now)}
+	}
+
+	code, err := randomCode(s.config.Length)
+	if err != nil {
+		return err
+	}
+
+	otp := &models.OTPCode{
+		Phone:     phone,
+		Purpose:   purpose,
+		CodeHash:  hashOTP(phone, purpose, code),
+		ExpiresAt: now.Add(s.config.TTL),
+	}
+	if err := s.otpRepository.Create(ctx, otp); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("Код подтверждения: %s. Никому не сообщайте его.", code)
+	if purpose == models.OTPLogin {
+		text = fmt.Sprintf("Код для входа: %s. Никому не сообщайте его.", code)
+	}
+	return s.smsProvider.Send(ctx, phone, text)
+}
+
+// randomCode код из length цифр
+func randomCode(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+// hashOTP хеш кода привязан к номеру и назначению, чтобы одинаковые коды давали разные хеши
+func hashOTP(phone string, purpose models.OTPPurpose, code string) string {
+	return hashToken(phone + ":" + string(purpose) + ":" + code)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/utils/password"
+	"net/url"
+	"time"
+)
+
+type PasswordResetService struct {
+	passwordResetRepository models.PasswordResetRepository
+	userRepository          models.UserRepository
+	employeeRepository      models.EmployeeRepository
+	sessionRepository       models.SessionRepository
+	mailer                  mailer.Mailer
+	config                  config.PasswordReset
+	contextTimeout          time.Duration
+}
+
+func NewPasswordResetService(
+	passwordResetRepository models.PasswordResetRepository,
+	userRepository models.UserRepository,
+	employeeRepository models.EmployeeRepository,
+	sessionRepository models.SessionRepository,
+	mailer mailer.Mailer,
+	config config.PasswordReset,
+	contextTimeout time.Duration,
+) *PasswordResetService {
+	return &PasswordResetService{
+		passwordResetRepository: passwordResetRepository,
+		userRepository:          userRepository,
+		employeeRepository:      employeeRepository,
+		sessionRepository:       sessionRepository,
+		mailer:                  mailer,
+		config:                  config,
+		contextTimeout:          contextTimeout,
+	}
+}
+
+// Forgot отправляет письмо со ссылкой для восстановления пароля. Клиент указывает телефон, сотрудник — почту.
+// Результат не зависит от того, найден ли аккаунт, чтобы по ответу нельзя было проверить его существование
+func (s *PasswordResetService) Forgot(c context.Context, phone string, email string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	account, err := findAccount(ctx, s.userRepository, s.employeeRepository, phone, email)
+	if err != nil {
+		log.Printf("password reset: account not found: %v", err)
+		return nil
+	}
+	if account.email == "" {
+		return nil
+	}
+	subject := account.subject
+
+	count, err := s.passwordResetRepository.CountSince(ctx, subject, time.Now().Add(-s.config.Window))
+	if err != nil {
+		return err
+	}
+	if count >= s.config.MaxRequests {
+		log.Printf("password reset: rate limit exceeded for %s %d", subject.Type, subject.Id)
+		return nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	reset := &models.PasswordReset{
+		Subject:   subject,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}
+	if err := s.passwordResetRepository.Create(ctx, reset); err != nil {
+		return err
+	}
+
+	message, err := s.message(account.email, token)
+	if err != nil {
+		return err
+	}
+
+	// письмо отправляется в фоне: время ответа не должно выдавать существование аккаунта
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.contextTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, message); err != nil {
+			log.Printf("password reset: failed to send mail to %s %d: %v", subject.Type, subject.Id, err)
+		}
+	}()
+	return nil
+}
+
+// Reset устанавливает новый пароль по токену из письма и завершает все сессии аккаунта
+func (s *PasswordResetService) Reset(c context.Context, token string, newPassword string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if token == "" {
+		return models.ErrInvalidResetToken
+	}
+	if err := password.Validate(newPassword); err != nil {
+		return err
+	}
+
+	hash, err := password.Encrypt(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	subject, err := s.passwordResetRepository.Reset(ctx, hashToken(token), hash)
+	if err != nil {
+		return err
+	}
+
+	return s.sessionRepository.RevokeSubject(ctx, subject)
+}
+
+func (s *PasswordResetService) message(to string, token string) (mailer.Message, error) {
+	link, err := url.Parse(s.config.URL)
+	if err != nil {
+		return mailer.Message{}, errors.New("invalid password reset url")
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return mailer.Message{
+		To:      to,
+		Subject: "Восстановление пароля",
+		Body: fmt.Sprintf(
+			"Для восстановления пароля перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует %d мин. и может быть использована один раз.\n"+
+				"Если вы не запрашивали восстановление пароля, проигнорируйте это письмо.\n",
+			link.String(),
+			int(s.config.TTL.Minutes()),
+		),
+	}, nil
+}
+
+// randomToken токен для ссылки восстановления пароля
+func randomToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
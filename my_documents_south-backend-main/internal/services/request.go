@@ -3,14 +3,18 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"my_documents_south_backend/internal/models"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type requestService struct {
 	requestRepository  models.RequestRepository
 	userRepository     models.UserRepository
 	employeeRepository models.EmployeeRepository
+	settings           *SettingService
 	contextTimeout     time.Duration
 }
 
@@ -18,12 +22,14 @@ func NewRequestService(
 	requestRepository models.RequestRepository,
 	userRepository models.UserRepository,
 	employeeRepository models.EmployeeRepository,
+	settings *SettingService,
 	contextTimeout time.Duration,
 ) models.RequestService {
 	return &requestService{
 		requestRepository:  requestRepository,
 		userRepository:     userRepository,
 		employeeRepository: employeeRepository,
+		settings:           settings,
 		contextTimeout:     contextTimeout,
 	}
 }
@@ -32,7 +38,36 @@ func (s *requestService) Create(c context.Context, req *models.Request) error {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
-	err := s.requestRepository.Create(ctx, req)
+	// новая заявка всегда начинает с начального статуса
+	req.Status = models.StatusNew
+
+	if req.OwnerId < 1 {
+		return models.ErrInvalidOwner
+	}
+	if err := s.userRepository.GetById(ctx, int(req.OwnerId), &models.User{}); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return models.ErrInvalidOwner
+		}
+		return err
+	}
+
+	settings, err := s.settings.Get(ctx)
+	if err != nil {
+		return err
+	}
+	// приоритет и срок по умолчанию берутся из настроек SLA
+	if req.Priority == 0 {
+		req.Priority = settings.SLADefaultPriority
+	}
+	if req.DesiredAt.IsZero() {
+		req.DesiredAt = time.Now().Add(time.Duration(settings.SLAResolutionHours) * time.Hour)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	err = s.requestRepository.Create(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -74,49 +109,247 @@ func (s *requestService) GetById(c context.Context, id int) (*models.Request, er
 	return req, nil
 }
 
-func (s *requestService) GetWithFilter(c context.Context, filter models.Request) ([]models.Request, error) {
+func (s *requestService) GetWithFilter(c context.Context, filter models.RequestFilter) (*models.RequestPage, error) {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
-	var requests []models.Request
-	err := s.requestRepository.GetWithFilter(ctx, &requests, filter)
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	total, err := s.requestRepository.CountWithFilter(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	for i := range requests {
-		req := &requests[i]
+	// лишняя заявка показывает, что за страницей есть продолжение
+	limit := filter.Limit
+	filter.Limit++
 
-		user := &models.User{}
-		if err := s.userRepository.GetById(ctx, int(req.OwnerId), user); err == nil {
-			req.User = user
+	requests := []models.Request{}
+	if err := s.requestRepository.GetWithFilter(ctx, &requests, filter); err != nil {
+		return nil, err
+	}
+
+	page := &models.RequestPage{Items: requests, Total: total}
+	if len(requests) > limit {
+		page.Items = requests[:limit]
+		page.NextCursor = models.NewRequestCursor(&page.Items[limit-1], filter.Sort).Encode()
+	}
+
+	if err := s.attachParticipants(ctx, page.Items); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// attachParticipants заполняет владельцев и исполнителей заявок двумя запросами на всю страницу
+func (s *requestService) attachParticipants(ctx context.Context, requests []models.Request) error {
+	var ownerIds, employeeIds []int64
+	for _, req := range requests {
+		ownerIds = append(ownerIds, req.OwnerId)
+		if req.EmployeeId != 0 {
+			employeeIds = append(employeeIds, req.EmployeeId)
 		}
+	}
 
-		employee := &models.Employee{}
-		if err := s.employeeRepository.GetById(ctx, int(req.EmployeeId), employee); err == nil {
-			req.Employee = employee
+	users := make(map[int64]*models.User)
+	if len(ownerIds) > 0 {
+		var list []models.User
+		if err := s.userRepository.GetByIds(ctx, ownerIds, &list); err != nil {
+			return err
 		}
+		for i := range list {
+			users[list[i].Id] = &list[i]
+		}
+	}
 
+	employees := make(map[int64]*models.Employee)
+	if len(employeeIds) > 0 {
+		var list []models.Employee
+		if err := s.employeeRepository.GetByIds(ctx, employeeIds, &list); err != nil {
+			return err
+		}
+		for i := range list {
+			employees[list[i].Id] = &list[i]
+		}
 	}
 
-	return requests, nil
+	for i := range requests {
+		requests[i].User = users[requests[i].OwnerId]
+		requests[i].Employee = employees[requests[i].EmployeeId]
+	}
+	return nil
 }
 
-// Update TODO
+// Update не используется, изменение заявки выполняет Patch
 func (s *requestService) Update(c context.Context, id int, req *models.Request) error { return nil }
 
-func (s *requestService) UpdateEmployee(ctx context.Context, id int64, employee_id int64) error {
+func (s *requestService) Patch(c context.Context, id int64, version int32, update models.RequestUpdate, actor models.Actor) (*models.Request, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(id), &req); err != nil {
+		return nil, err
+	}
+	if version != 0 && req.Version != version {
+		return nil, models.ErrRequestModified
+	}
+	if req.Status.Terminal() {
+		return nil, models.ErrRequestClosed
+	}
+
+	if update.Name != nil {
+		req.Name = strings.TrimSpace(*update.Name)
+	}
+	if update.Desc != nil {
+		req.Desc = strings.TrimSpace(*update.Desc)
+	}
+	if update.Priority != nil {
+		req.Priority = *update.Priority
+	}
+	if update.ServiceId != nil {
+		req.ServiceId = *update.ServiceId
+	}
+	if update.DesiredAt != nil {
+		req.DesiredAt = *update.DesiredAt
+	}
+
+	if err := validateRequest(&req); err != nil {
+		return nil, err
+	}
+	if err := s.requestRepository.UpdateDetails(ctx, &req, actor); err != nil {
+		return nil, err
+	}
+
+	return s.GetById(ctx, int(id))
+}
+
+func validateRequest(req *models.Request) error {
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 255 {
+		return fmt.Errorf("%w: name must contain from 1 to 255 characters", models.ErrInvalidRequest)
+	}
+	if req.Priority < 0 {
+		return models.ErrInvalidPriority
+	}
+	if req.ServiceId < 0 {
+		return fmt.Errorf("%w: invalid service_id", models.ErrInvalidRequest)
+	}
+	if req.DesiredAt.IsZero() {
+		return fmt.Errorf("%w: desired_at is required", models.ErrInvalidRequest)
+	}
+	return nil
+}
+
+func (s *requestService) UpdateEmployee(ctx context.Context, id int64, employee_id int64, actor models.Actor) error {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(id), &req); err != nil {
+		return err
+	}
+	if req.Status.Terminal() {
+		return models.ErrRequestClosed
+	}
+
+	var employee models.Employee
+	if err := s.employeeRepository.GetById(ctx, int(employee_id), &employee); err != nil {
+		if errors.Is(err, models.ErrEmployeeNotFound) {
+			return models.ErrInvalidAssignee
+		}
+		return err
+	}
+	if !employee.Active {
+		return models.ErrInvalidAssignee
+	}
+
+	return s.requestRepository.UpdateEmployee(ctx, id, employee_id, actor)
+}
+
+func (s *requestService) UpdateStatus(ctx context.Context, id int64, status models.RequestStatus, actor models.Actor) error {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	if !status.Valid() {
+		return models.ErrInvalidStatus
+	}
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(id), &req); err != nil {
+		return err
+	}
+
+	if !req.Status.CanTransition(status, actor) {
+		return &models.TransitionError{From: req.Status, To: status, Allowed: req.Status.Transitions(actor)}
+	}
+
+	return s.requestRepository.UpdateStatus(ctx, id, req.Status, status, actor)
+}
+
+func (s *requestService) Transitions(ctx context.Context, id int64, actor models.Actor) ([]models.RequestStatus, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
 	defer cancel()
 
-	return s.requestRepository.UpdateEmployee(ctx, id, employee_id)
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(id), &req); err != nil {
+		return nil, err
+	}
+
+	return req.Status.Transitions(actor), nil
 }
 
-func (s *requestService) UpdateStatus(ctx context.Context, id int64, status int16) error {
+func (s *requestService) UpdatePriority(ctx context.Context, id int64, priority int16, actor models.Actor) error {
 	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
 	defer cancel()
 
-	return s.requestRepository.UpdateStatus(ctx, id, status)
+	if priority < 0 {
+		return models.ErrInvalidPriority
+	}
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(id), &req); err != nil {
+		return err
+	}
+	if req.Status.Terminal() {
+		return models.ErrRequestClosed
+	}
+
+	return s.requestRepository.UpdatePriority(ctx, id, priority, actor)
+}
+
+func (s *requestService) AddComment(ctx context.Context, id int64, comment string, actor models.Actor) (*models.RequestEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	comment = strings.TrimSpace(comment)
+	if comment == "" || utf8.RuneCountInString(comment) > 4000 {
+		return nil, models.ErrInvalidComment
+	}
+
+	event := &models.RequestEvent{}
+	if err := s.requestRepository.AddComment(ctx, id, comment, actor, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *requestService) GetHistory(ctx context.Context, id int64) ([]models.RequestEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.contextTimeout)
+	defer cancel()
+
+	var req models.Request
+	if err := s.requestRepository.GetById(ctx, int(id), &req); err != nil {
+		return nil, err
+	}
+
+	events := []models.RequestEvent{}
+	if err := s.requestRepository.GetHistory(ctx, id, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
 }
 
 func (s *requestService) Delete(c context.Context, id int) error {
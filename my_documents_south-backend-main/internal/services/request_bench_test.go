@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"my_documents_south_backend/internal/models"
+	"testing"
+	"time"
+)
+
+// benchRequestRepository хранит rows заявок с разными владельцами и исполнителями
+// и отдаёт их страницами, как репозиторий в Postgres
+type benchRequestRepository struct {
+	models.RequestRepository
+	rows []models.Request
+}
+
+func (r *benchRequestRepository) CountWithFilter(_ context.Context, _ models.RequestFilter) (int, error) {
+	return len(r.rows), nil
+}
+
+func (r *benchRequestRepository) GetWithFilter(_ context.Context, requests *[]models.Request, filter models.RequestFilter) error {
+	*requests = append((*requests)[:0], r.rows[:min(filter.Limit, len(r.rows))]...)
+	return nil
+}
+
+// benchUserRepository считает запросы владельцев
+type benchUserRepository struct {
+	models.UserRepository
+	calls int
+}
+
+func (r *benchUserRepository) GetByIds(_ context.Context, ids []int64, users *[]models.User) error {
+	r.calls++
+	for _, id := range ids {
+		*users = append(*users, models.User{Id: id})
+	}
+	return nil
+}
+
+// benchEmployeeRepository считает запросы исполнителей
+type benchEmployeeRepository struct {
+	models.EmployeeRepository
+	calls int
+}
+
+func (r *benchEmployeeRepository) GetByIds(_ context.Context, ids []int64, employees *[]models.Employee) error {
+	r.calls++
+	for _, id := range ids {
+		*employees = append(*employees, models.Employee{Id: id})
+	}
+	return nil
+}
+
+// BenchmarkRequestGetWithFilter проверяет, что владельцы и исполнители загружаются одним запросом
+// на страницу независимо от её размера. Страница ограничена MaxRequestLimit, поэтому при 1000 заявках
+// загружается первая страница из MaxRequestLimit заявок
+func BenchmarkRequestGetWithFilter(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			requests := &benchRequestRepository{rows: make([]models.Request, size)}
+			for i := range requests.rows {
+				requests.rows[i] = models.Request{
+					Id:         int64(i + 1),
+					OwnerId:    int64(i + 1),
+					EmployeeId: int64(i + 1),
+					CreatedAt:  time.Now(),
+				}
+			}
+			users := &benchUserRepository{}
+			employees := &benchEmployeeRepository{}
+			service := NewRequestService(requests, users, employees, nil, time.Second)
+
+			b.ReportAllocs()
+			for b.Loop() {
+				users.calls, employees.calls = 0, 0
+
+				page, err := service.GetWithFilter(context.Background(), models.RequestFilter{Limit: size})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if users.calls != 1 || employees.calls != 1 {
+					b.Fatalf("expected one GetByIds per repository, got users=%d employees=%d", users.calls, employees.calls)
+				}
+				if len(page.Items) != min(size, models.MaxRequestLimit) || page.Items[0].User == nil || page.Items[0].Employee == nil {
+					b.Fatalf("participants are not attached to page of %d requests", len(page.Items))
+				}
+			}
+		})
+	}
+}
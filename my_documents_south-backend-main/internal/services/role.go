@@ -10,14 +10,12 @@ import (
 type roleService struct {
 	roleRepository models.RoleRepository
 	contextTimeout time.Duration
-	counterRole    int
 }
 
 func NewRoleService(roleRepository models.RoleRepository, contextTimeout time.Duration) models.RoleService {
 	return &roleService{
 		roleRepository: roleRepository,
 		contextTimeout: contextTimeout,
-		counterRole:    0,
 	}
 }
 
@@ -29,24 +27,9 @@ func (s *roleService) Create(c context.Context, role *models.Role) error {
 		return err
 	}
 
-	if s.counterRole != 1 {
-		return nil
-	}
-
-	// если роль создается первый раз, делаем её главной
-	// проверяем, существует ли уже суперроль. например, если счетчик обнулился при рестарте сервера
-	var superrole models.Role
-	err := s.roleRepository.GetSuperRole(c, &superrole)
-	if err == nil {
-		return nil
-	}
-
-	err = s.roleRepository.SetSuperRole(c, role.Id)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// если суперроль ещё не задана, например до первоначальной настройки, ей становится созданная роль
+	_, err := s.roleRepository.SetSuperRoleIfUnset(ctx, role.Id)
+	return err
 }
 
 func (s *roleService) Get(c context.Context) *[]models.Role {
@@ -97,7 +80,75 @@ func (s *roleService) Delete(c context.Context, id int) error {
 	return nil
 }
 
-func (s *roleService) count() int {
-	s.counterRole++
-	return s.counterRole
+func (s *roleService) GetPermissions(c context.Context, id int) (*models.RolePermissions, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return nil, errors.New("incorrect value id")
+	}
+
+	super, err := s.roleRepository.IsSuperRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := &models.RolePermissions{RoleId: id, Super: super, Permissions: []models.Permission{}}
+	if err := s.roleRepository.GetPermissions(ctx, id, &permissions.Permissions); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (s *roleService) SetPermissions(c context.Context, id int, permissions []models.Permission) (*models.RolePermissions, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if id < 1 {
+		return nil, errors.New("incorrect value id")
+	}
+
+	permissions, err := models.ValidatePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepository.SetPermissions(ctx, id, permissions); err != nil {
+		return nil, err
+	}
+
+	return s.GetPermissions(c, id)
+}
+
+// checkRoleGrant проверяет, что assigner может выдать роль roleId: все права роли должны быть у его роли,
+// а суперроль может выдать только сотрудник с суперролью
+func checkRoleGrant(ctx context.Context, roleService models.RoleService, assigner *models.Principal, roleId int) error {
+	if assigner.RoleId == nil {
+		return models.ErrForbidden
+	}
+
+	own, err := roleService.GetPermissions(ctx, *assigner.RoleId)
+	if err != nil {
+		return err
+	}
+	if own.Super {
+		return nil
+	}
+
+	target, err := roleService.GetPermissions(ctx, roleId)
+	if err != nil {
+		return err
+	}
+	if target.Super {
+		return models.ErrForbidden
+	}
+
+	granted := own.Set()
+	for _, permission := range target.Permissions {
+		if !granted.Has(permission) {
+			return models.ErrForbidden
+		}
+	}
+	return nil
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"html"
+	"my_documents_south_backend/internal/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type searchService struct {
+	searchRepository models.SearchRepository
+	contextTimeout   time.Duration
+}
+
+func NewSearchService(searchRepository models.SearchRepository, timeout time.Duration) models.SearchService {
+	return &searchService{
+		searchRepository: searchRepository,
+		contextTimeout:   timeout,
+	}
+}
+
+// Search ищет заявки и сообщения полнотекстово, клиентов — по фрагменту ФИО, телефона, ИНН и СНИЛС.
+// Сотрудник без request.read_all видит только назначенные ему заявки, без user.read_all — не ищет клиентов
+func (s *searchService) Search(c context.Context, principal *models.Principal, query string, limit int) (*models.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	query = strings.Join(strings.Fields(query), " ")
+	if length := utf8.RuneCountInString(query); length < 2 || length > 200 {
+		return nil, models.ErrInvalidSearchQuery
+	}
+
+	if limit < 1 {
+		limit = models.DefaultSearchLimit
+	}
+	limit = min(limit, models.MaxSearchLimit)
+
+	scope := models.SearchScope{Users: principal.Can(models.PermUserReadAll)}
+	if !principal.Can(models.PermRequestReadAll) {
+		employeeId := principal.Id
+		scope.EmployeeId = &employeeId
+	}
+
+	result := &models.SearchResult{
+		Requests: []models.SearchHit{},
+		Users:    []models.SearchHit{},
+		Messages: []models.SearchHit{},
+	}
+
+	if err := s.searchRepository.SearchRequests(ctx, query, scope, limit, &result.Requests); err != nil {
+		return nil, err
+	}
+	for i := range result.Requests {
+		result.Requests[i].Type = models.SearchHitRequest
+		result.Requests[i].Snippet = renderSnippet(result.Requests[i].Snippet)
+	}
+
+	if err := s.searchRepository.SearchMessages(ctx, query, scope, limit, &result.Messages); err != nil {
+		return nil, err
+	}
+	for i := range result.Messages {
+		result.Messages[i].Type = models.SearchHitMessage
+		result.Messages[i].Snippet = renderSnippet(result.Messages[i].Snippet)
+	}
+
+	if scope.Users {
+		digits := searchDigits(query)
+		if err := s.searchRepository.SearchUsers(ctx, query, escapeLike(query), digits, limit, &result.Users); err != nil {
+			return nil, err
+		}
+		for i := range result.Users {
+			hit := &result.Users[i]
+			hit.Type = models.SearchHitUser
+			needle := query
+			if digits != "" && strings.Contains(hit.Snippet, digits) {
+				needle = digits
+			}
+			hit.Snippet = renderSnippet(markMatch(hit.Snippet, needle))
+		}
+	}
+
+	return result, nil
+}
+
+// searchDigits возвращает цифры запроса, если он похож на фрагмент телефона, ИНН или СНИЛС
+// ("+7 999 123-45", "123-456"), иначе пустую строку
+func searchDigits(query string) string {
+	var digits strings.Builder
+	for _, r := range query {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" +-()", r):
+		default:
+			return ""
+		}
+	}
+	if digits.Len() < 3 {
+		return ""
+	}
+	return digits.String()
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// markMatch выделяет первое вхождение needle в text без учёта регистра
+func markMatch(text string, needle string) string {
+	index := strings.Index(strings.ToLower(text), strings.ToLower(needle))
+	end := index + len(needle)
+	// при смене длины символа в нижнем регистре смещения не совпадают, тогда фрагмент не выделяется
+	if index < 0 || end > len(text) || !strings.EqualFold(text[index:end], needle) {
+		return text
+	}
+	return text[:index] + models.SnippetStart + text[index:end] + models.SnippetStop + text[end:]
+}
+
+// renderSnippet экранирует фрагмент и заменяет маркеры совпадений на <mark>
+func renderSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(models.SnippetStart, "<mark>", models.SnippetStop, "</mark>").Replace(snippet)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"my_documents_south_backend/internal/models"
+	"testing"
+)
+
+func TestSearchDigits(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"+7 999 123-45-67", "79991234567"},
+		{"(495) 12", "49512"},
+		{"123-456", "123456"},
+		{"123", "123"},
+		{"12", ""},
+		{"1-2", ""},
+		{"иванов 123", ""},
+		{"123.456", ""},
+		{"+-()", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := searchDigits(tt.query); got != tt.want {
+			t.Errorf("searchDigits(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"иванов", "иванов"},
+		{"100%", `100\%`},
+		{"a_b", `a\_b`},
+		{`c:\dir`, `c:\\dir`},
+		{`\%_`, `\\\%\_`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.value); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMarkMatch(t *testing.T) {
+	mark := func(s string) string {
+		return models.SnippetStart + s + models.SnippetStop
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		needle string
+		want   string
+	}{
+		{"case insensitive", "Иванов Иван", "иван", mark("Иван") + "ов Иван"},
+		{"first match only", "abc abc", "abc", mark("abc") + " abc"},
+		{"digits", "79991234567", "123", "7999" + mark("123") + "4567"},
+		{"no match", "Петров", "иван", "Петров"},
+		// 'İ' в нижнем регистре длиннее в байтах, смещения не совпадают
+		{"length changing case", "İstanbul", "stan", "İstanbul"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markMatch(tt.text, tt.needle); got != tt.want {
+				t.Errorf("markMatch(%q, %q) = %q, want %q", tt.text, tt.needle, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"plain", "plain"},
+		{"a " + models.SnippetStart + "match" + models.SnippetStop + " b", "a <mark>match</mark> b"},
+		{"<script>" + models.SnippetStart + "x" + models.SnippetStop, "&lt;script&gt;<mark>x</mark>"},
+		{`"q" & 'a'`, "&#34;q&#34; &amp; &#39;a&#39;"},
+	}
+
+	for _, tt := range tests {
+		if got := renderSnippet(tt.snippet); got != tt.want {
+			t.Errorf("renderSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
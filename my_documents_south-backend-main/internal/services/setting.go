@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"my_documents_south_backend/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dongri/phonenumber"
+)
+
+// settingsCacheTTL время жизни кеша настроек. Запись сбрасывает кеш сразу, TTL нужен,
+// чтобы изменения, сделанные другими экземплярами приложения, тоже применились
+const settingsCacheTTL = time.Minute
+
+// maxResolutionHours наибольший срок выполнения заявки по умолчанию, год
+const maxResolutionHours = 365 * 24
+
+type SettingService struct {
+	settingRepository models.SettingRepository
+	roleRepository    models.RoleRepository
+	tariffRepository  models.TariffRepository
+	// maxUploadSize предел размера документа из конфигурации, настройка не может его превышать
+	maxUploadSize  int64
+	contextTimeout time.Duration
+
+	mu       sync.RWMutex
+	cached   *models.Settings
+	cachedAt time.Time
+	// generation увеличивается при каждой записи, чтобы не закешировать значение, прочитанное до неё
+	generation uint64
+}
+
+func NewSettingService(
+	settingRepository models.SettingRepository,
+	roleRepository models.RoleRepository,
+	tariffRepository models.TariffRepository,
+	maxUploadSize int64,
+	contextTimeout time.Duration,
+) *SettingService {
+	return &SettingService{
+		settingRepository: settingRepository,
+		roleRepository:    roleRepository,
+		tariffRepository:  tariffRepository,
+		maxUploadSize:     maxUploadSize,
+		contextTimeout:    contextTimeout,
+	}
+}
+
+// Get возвращает настройки из кеша или из базы
+func (s *SettingService) Get(c context.Context) (*models.Settings, error) {
+	s.mu.RLock()
+	cached, cachedAt, generation := s.cached, s.cachedAt, s.generation
+	s.mu.RUnlock()
+	if cached != nil && time.Since(cachedAt) < settingsCacheTTL {
+		return copySettings(cached), nil
+	}
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var settings models.Settings
+	if err := s.settingRepository.Get(ctx, &settings); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		s.cached, s.cachedAt = copySettings(&settings), time.Now()
+	}
+	s.mu.Unlock()
+
+	return &settings, nil
+}
+
+// Update проверяет и сохраняет все настройки. Назначить другую суперроль может только сотрудник с суперролью
+func (s *SettingService) Update(c context.Context, principal *models.Principal, settings *models.Settings) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	current, err := s.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(ctx, settings); err != nil {
+		return err
+	}
+	if !principal.Super && !sameId(current.SuperuserRoleId, settings.SuperuserRoleId) {
+		return models.ErrForbidden
+	}
+
+	err = s.settingRepository.Update(ctx, settings)
+	s.invalidate()
+	return err
+}
+
+func (s *SettingService) invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.generation++
+	s.mu.Unlock()
+}
+
+func (s *SettingService) validate(ctx context.Context, settings *models.Settings) error {
+	if settings.SuperuserRoleId == nil {
+		return fmt.Errorf("%w: superuser_role_id is required", models.ErrInvalidSettings)
+	}
+	if err := s.roleRepository.GetById(ctx, *settings.SuperuserRoleId, &models.Role{}); err != nil {
+		return fmt.Errorf("%w: role %d not found", models.ErrInvalidSettings, *settings.SuperuserRoleId)
+	}
+
+	if settings.DefaultTariffId == nil {
+		return fmt.Errorf("%w: default_tariff_id is required", models.ErrInvalidSettings)
+	}
+	if err := s.tariffRepository.GetById(ctx, *settings.DefaultTariffId, &models.Tariff{}); err != nil {
+		return fmt.Errorf("%w: tariff %d not found", models.ErrInvalidSettings, *settings.DefaultTariffId)
+	}
+
+	if settings.MaxUploadSize < 1 || settings.MaxUploadSize > s.maxUploadSize {
+		return fmt.Errorf("%w: max_upload_size must be between 1 and %d", models.ErrInvalidSettings, s.maxUploadSize)
+	}
+	if settings.SLADefaultPriority < 0 {
+		return fmt.Errorf("%w: sla_default_priority must not be negative", models.ErrInvalidSettings)
+	}
+	if settings.SLAResolutionHours < 1 || settings.SLAResolutionHours > maxResolutionHours {
+		return fmt.Errorf("%w: sla_resolution_hours must be between 1 and %d", models.ErrInvalidSettings, maxResolutionHours)
+	}
+
+	settings.SupportEmail = strings.ToLower(strings.TrimSpace(settings.SupportEmail))
+	if settings.SupportEmail != "" && !emailPattern.MatchString(settings.SupportEmail) {
+		return fmt.Errorf("%w: invalid support_email format", models.ErrInvalidSettings)
+	}
+
+	if phone := strings.TrimSpace(settings.SupportPhone); phone != "" {
+		settings.SupportPhone = phonenumber.Parse(phone, "RU")
+		if settings.SupportPhone == "" {
+			return fmt.Errorf("%w: invalid support_phone", models.ErrInvalidSettings)
+		}
+	} else {
+		settings.SupportPhone = ""
+	}
+
+	return nil
+}
+
+// copySettings копирует настройки вместе со значениями указателей, чтобы вызывающий не мог изменить кеш
+func copySettings(settings *models.Settings) *models.Settings {
+	result := *settings
+	if settings.DefaultTariffId != nil {
+		id := *settings.DefaultTariffId
+		result.DefaultTariffId = &id
+	}
+	if settings.SuperuserRoleId != nil {
+		id := *settings.SuperuserRoleId
+		result.SuperuserRoleId = &id
+	}
+	if settings.UpdatedAt != nil {
+		updatedAt := *settings.UpdatedAt
+		result.UpdatedAt = &updatedAt
+	}
+	return &result
+}
+
+func sameId(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -10,14 +10,12 @@ import (
 type tariffService struct {
 	tariffRepository models.TariffRepository
 	contextTimeout   time.Duration
-	counterTariff    int
 }
 
 func NewTariffService(tariffRepository models.TariffRepository, contextTimeout time.Duration) models.TariffService {
 	return &tariffService{
 		tariffRepository: tariffRepository,
 		contextTimeout:   contextTimeout,
-		counterTariff:    0,
 	}
 }
 
@@ -30,24 +28,9 @@ func (s *tariffService) Create(c context.Context, tariff *models.Tariff) error {
 		return err
 	}
 
-	if s.count() != 1 {
-		return nil
-	}
-
-	// если тариф создается первый раз, делаем её по умолчанию
-	// проверяем, существует ли уже тариф по умолчанию. например, если счетчик обнулился при рестарте сервера
-	var default_tariff models.Tariff
-	err = s.tariffRepository.GetDefault(c, &default_tariff)
-	if err == nil {
-		return nil
-	}
-
-	err = s.tariffRepository.SetDefault(c, tariff.Id)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// если тариф по умолчанию ещё не задан, им становится созданный тариф
+	_, err = s.tariffRepository.SetDefaultIfUnset(ctx, tariff.Id)
+	return err
 }
 
 func (s *tariffService) Get(c context.Context) *[]models.Tariff {
@@ -106,8 +89,3 @@ func (s *tariffService) Delete(c context.Context, id int) error {
 
 	return s.tariffRepository.Delete(ctx, id)
 }
-
-func (s *tariffService) count() int {
-	s.counterTariff++
-	return s.counterTariff
-}
@@ -6,59 +6,60 @@ import (
 	"fmt"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/utils/password"
-	"regexp"
+	"strings"
 	"time"
-	"unicode"
+	"unicode/utf8"
 
 	"github.com/dongri/phonenumber"
 )
 
 type userService struct {
-	userRepository   models.UserRepository
-	tariffRepository models.TariffRepository
-	contextTimeout   time.Duration
+	userRepository    models.UserRepository
+	tariffRepository  models.TariffRepository
+	sessionRepository models.SessionRepository
+	settings          *SettingService
+	contextTimeout    time.Duration
 }
 
-func NewUserService(userRepository models.UserRepository, tariffRepository models.TariffRepository, contextTimeout time.Duration) models.UserService {
-	return &userService{userRepository: userRepository, tariffRepository: tariffRepository, contextTimeout: contextTimeout}
+func NewUserService(
+	userRepository models.UserRepository,
+	tariffRepository models.TariffRepository,
+	sessionRepository models.SessionRepository,
+	settings *SettingService,
+	contextTimeout time.Duration,
+) models.UserService {
+	return &userService{
+		userRepository:    userRepository,
+		tariffRepository:  tariffRepository,
+		sessionRepository: sessionRepository,
+		settings:          settings,
+		contextTimeout:    contextTimeout,
+	}
 }
 
 func (s *userService) Create(c context.Context, user *models.User) error {
 	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
 	defer cancel()
 
-	hasLetter := false
-	hasDigit := false
-	normalized := phonenumber.Parse(user.Phone, "RU")
-
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-	if !emailRegex.MatchString(user.Email) {
-		return errors.New("invalid email format")
+	settings, err := s.settings.Get(ctx)
+	if err != nil {
+		return err
 	}
-
-	if normalized == "" {
-		return errors.New("invalid phone number")
+	if !settings.SignupOpen {
+		return models.ErrSignupClosed
 	}
-	user.Phone = normalized
 
-	if len(user.Password) < 6 {
-		return errors.New("invalid password: must contain at least 6 characters")
+	// почта приводится к нижнему регистру, как при изменении клиента
+	if err := validateUser(user); err != nil {
+		return err
 	}
 
-	for _, ch := range user.Password {
-		if unicode.IsLetter(ch) {
-			hasLetter = true
-		}
-		if unicode.IsDigit(ch) {
-			hasDigit = true
-		}
-	}
-	if !hasLetter || !hasDigit {
-		return errors.New("invalid password: must contain at least one letter and one digit")
+	if err := password.Validate(user.Password); err != nil {
+		return err
 	}
 
 	var tariff models.Tariff
-	err := s.tariffRepository.GetDefault(ctx, &tariff)
+	err = s.tariffRepository.GetDefault(ctx, &tariff)
 	if err != nil {
 		return fmt.Errorf("failed to check default tariff: %w", err)
 	}
@@ -106,10 +107,82 @@ func (s *userService) GetById(c context.Context, id int) (*models.User, error) {
 	return user, nil
 }
 
+// Update сохраняет имя, контакты, ИНН и СНИЛС клиента id после проверки. Пароль и тариф не меняются
 func (s *userService) Update(c context.Context, id int, user *models.User) error {
-	// TODO update user service
-	// DONT TOUCH
-	return nil
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	user.Id = int64(id)
+	if err := validateUser(user); err != nil {
+		return err
+	}
+	return s.userRepository.Update(ctx, user)
+}
+
+func (s *userService) Patch(c context.Context, id int64, update models.UserUpdate) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	var user models.User
+	if err := s.userRepository.GetById(ctx, int(id), &user); err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		user.Name = *update.Name
+	}
+	if update.LastName != nil {
+		user.LastName = *update.LastName
+	}
+	if update.MiddleName != nil {
+		user.MiddleName = *update.MiddleName
+	}
+	if update.Email != nil {
+		user.Email = *update.Email
+	}
+	if update.Phone != nil {
+		user.Phone = *update.Phone
+	}
+	if update.Inn != nil {
+		user.Inn = *update.Inn
+	}
+	if update.Snils != nil {
+		user.Snils = *update.Snils
+	}
+
+	if err := validateUser(&user); err != nil {
+		return nil, err
+	}
+	if err := s.userRepository.Update(ctx, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *userService) ChangePassword(c context.Context, principal *models.Principal, current string, next string) error {
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	hash, err := s.userRepository.GetPassword(ctx, principal.Id)
+	if err != nil {
+		return err
+	}
+	if err := password.Compare(hash, current); err != nil {
+		return models.ErrWrongPassword
+	}
+
+	if err := password.Validate(next); err != nil {
+		return err
+	}
+	hash, err = password.Encrypt(next)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := s.userRepository.SetPassword(ctx, principal.Id, hash); err != nil {
+		return err
+	}
+	return s.sessionRepository.RevokeOthers(ctx, principal.Actor(), principal.SessionId)
 }
 
 func (s *userService) Delete(c context.Context, id int) error {
@@ -118,3 +191,50 @@ func (s *userService) Delete(c context.Context, id int) error {
 
 	return s.userRepository.Delete(ctx, id)
 }
+
+// validateUser нормализует и проверяет имя, контакты, ИНН и СНИЛС клиента
+func validateUser(user *models.User) error {
+	user.Name = strings.TrimSpace(user.Name)
+	user.LastName = strings.TrimSpace(user.LastName)
+	user.MiddleName = strings.TrimSpace(user.MiddleName)
+	if user.Name == "" || user.LastName == "" {
+		return fmt.Errorf("%w: name and last_name are required", models.ErrInvalidUser)
+	}
+	if utf8.RuneCountInString(user.Name) > 100 || utf8.RuneCountInString(user.LastName) > 100 ||
+		utf8.RuneCountInString(user.MiddleName) > 100 {
+		return fmt.Errorf("%w: names must not exceed 100 characters", models.ErrInvalidUser)
+	}
+
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	if !emailPattern.MatchString(user.Email) || len(user.Email) > 255 {
+		return fmt.Errorf("%w: invalid email format", models.ErrInvalidUser)
+	}
+
+	user.Phone = phonenumber.Parse(user.Phone, "RU")
+	if user.Phone == "" {
+		return fmt.Errorf("%w: invalid phone number", models.ErrInvalidUser)
+	}
+
+	// ИНН физического лица — 12 цифр, организации — 10
+	user.Inn = strings.TrimSpace(user.Inn)
+	if user.Inn != "" && (!onlyDigits(user.Inn) || len(user.Inn) != 10 && len(user.Inn) != 12) {
+		return fmt.Errorf("%w: inn must contain 10 or 12 digits", models.ErrInvalidUser)
+	}
+
+	// СНИЛС часто записывают как 123-456-789 01
+	user.Snils = strings.NewReplacer("-", "", " ", "").Replace(user.Snils)
+	if !onlyDigits(user.Snils) || len(user.Snils) != 11 {
+		return fmt.Errorf("%w: snils must contain 11 digits", models.ErrInvalidUser)
+	}
+
+	return nil
+}
+
+func onlyDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,37 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileProvider дописывает сообщения в файл, по одному на строку. Используется в тестах и при разработке
+type FileProvider struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sms directory: %w", err)
+	}
+	return &FileProvider{path: path}, nil
+}
+
+func (p *FileProvider) Send(_ context.Context, phone string, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := os.OpenFile(p.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%q\n", time.Now().Format(time.RFC3339), phone, text)
+	return err
+}
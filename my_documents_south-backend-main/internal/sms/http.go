@@ -0,0 +1,54 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"my_documents_south_backend/internal/config"
+	"net/http"
+)
+
+// HTTPProvider отправляет SMS через HTTP шлюз: POST JSON {"to", "text", "sender"}
+// с заголовком Authorization: Bearer <token>. Успехом считается любой ответ 2xx
+type HTTPProvider struct {
+	cfg    config.HTTPSMS
+	client *http.Client
+}
+
+func NewHTTPProvider(cfg config.HTTPSMS) *HTTPProvider {
+	return &HTTPProvider{cfg: cfg, client: &http.Client{}}
+}
+
+func (p *HTTPProvider) Send(ctx context.Context, phone string, text string) error {
+	payload, err := json.Marshal(map[string]string{
+		"to":     phone,
+		"text":   text,
+		"sender": p.cfg.Sender,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.Token)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send sms: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("sms gateway responded %d: %s", res.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package sms
+
+import (
+	"context"
+	"log"
+)
+
+// LogProvider пишет сообщения в лог вместо отправки
+type LogProvider struct{}
+
+func NewLogProvider() *LogProvider {
+	return &LogProvider{}
+}
+
+func (p *LogProvider) Send(_ context.Context, phone string, text string) error {
+	log.Printf("sms: to=%s %q", phone, text)
+	return nil
+}
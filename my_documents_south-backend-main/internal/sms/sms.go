@@ -0,0 +1,26 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"my_documents_south_backend/internal/config"
+)
+
+// SMSProvider отправляет SMS на номер в формате E.164 без знака +
+type SMSProvider interface {
+	Send(ctx context.Context, phone string, text string) error
+}
+
+// New создаёт провайдера по настройкам sms.driver
+func New(cfg config.SMS) (SMSProvider, error) {
+	switch cfg.Driver {
+	case config.SMSHTTP:
+		return NewHTTPProvider(cfg.HTTP), nil
+	case config.SMSFile:
+		return NewFileProvider(cfg.File.Path)
+	case config.SMSLog:
+		return NewLogProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown sms driver %q", cfg.Driver)
+	}
+}
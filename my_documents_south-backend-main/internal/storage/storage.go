@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"my_documents_south_backend/internal/config"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore хранилище содержимого файлов. Метаданные файлов хранятся в Postgres
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New создаёт хранилище по настройкам storage.driver
+func New(cfg config.Storage) (BlobStore, error) {
+	switch cfg.Driver {
+	case config.StorageLocal:
+		return NewLocalStore(cfg.Local.Root)
+	case config.StorageS3:
+		return NewS3Store(context.Background(), cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
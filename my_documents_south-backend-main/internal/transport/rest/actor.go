@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"my_documents_south_backend/internal/middleware"
+	"my_documents_south_backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func principalFromCtx(c *fiber.Ctx) (*models.Principal, error) {
+	principal, ok := middleware.PrincipalFromCtx(c)
+	if !ok {
+		return nil, errors.New("invalid principal in token")
+	}
+	return principal, nil
+}
+
+// actorFromCtx определяет инициатора запроса по данным JWT
+func actorFromCtx(c *fiber.Ctx) (models.Actor, error) {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return models.Actor{}, err
+	}
+	return principal.Actor(), nil
+}
+
+// requestAccess пропускает запрос к заявке :id, только если она доступна инициатору
+func requestAccess(requestRepo models.RequestRepository, timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+		}
+
+		principal, err := principalFromCtx(c)
+		if err != nil {
+			res := models.NewErrorResponse(err, c.Path()).Log()
+			return c.Status(fiber.StatusUnauthorized).JSON(res)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), timeout)
+		defer cancel()
+
+		var req models.Request
+		if err := requestRepo.GetById(ctx, int(id), &req); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				res := models.NewErrorResponse(errors.New("request not found"), c.Path()).Log()
+				return c.Status(fiber.StatusNotFound).JSON(res)
+			}
+			res := models.NewErrorResponse(err, c.Path()).Log()
+			return c.Status(fiber.StatusInternalServerError).JSON(res)
+		}
+
+		if !principal.CanAccessRequest(&req) {
+			res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+			return c.Status(fiber.StatusForbidden).JSON(res)
+		}
+
+		return c.Next()
+	}
+}
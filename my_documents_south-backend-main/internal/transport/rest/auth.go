@@ -2,19 +2,29 @@ package rest
 
 import (
 	"errors"
+	"math"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/memory"
+	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/sms"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
 )
 
 type AuthHandler struct {
 	authService *services.AuthService
+	otpService  *services.OTPService
+	mfaService  *services.MFAService
 }
 
-func NewAuthHander(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHander(authService *services.AuthService, otpService *services.OTPService, mfaService *services.MFAService) *AuthHandler {
+	return &AuthHandler{authService: authService, otpService: otpService, mfaService: mfaService}
 }
 
 func (h *AuthHandler) loginUser(c *fiber.Ctx) error {
@@ -25,10 +35,9 @@ func (h *AuthHandler) loginUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
 	}
 
-	token, err := h.authService.LoginUser(c.Context(), &user)
+	token, err := h.authService.LoginUser(c.Context(), &user, sessionClient(c))
 	if err != nil {
-		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusConflict).JSON(res)
+		return loginError(c, err)
 	}
 
 	return c.JSON(token)
@@ -36,8 +45,8 @@ func (h *AuthHandler) loginUser(c *fiber.Ctx) error {
 
 func (h *AuthHandler) refreshToken(c *fiber.Ctx) error {
 	// Получаем ТОЛЬКО refresh_token
-	var token models.JwtToken
-	if err := c.BodyParser(&token); err != nil {
+	var body models.JwtToken
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Invalid request body",
@@ -45,23 +54,151 @@ func (h *AuthHandler) refreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	userID, ok := c.Locals("userID").(int64)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(errors.New("invalid user ID in token"), c.Path()).Log())
+	token, err := h.authService.RefreshToken(c.Context(), body.RefreshToken, sessionClient(c))
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		if errors.Is(err, models.ErrInvalidRefreshToken) || errors.Is(err, models.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(res)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.JSON(token)
+}
+
+func (h *AuthHandler) logout(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	if err := h.authService.Logout(c.Context(), principal); err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
 	}
 
-	roleInt, ok := c.Locals("roleID").(int)
-	var roleID *int = nil
-	if ok {
-		roleID = &roleInt
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AuthHandler) logoutAll(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
 	}
 
-	if err := h.authService.RefreshToken(userID, roleID, &token); err != nil {
+	if err := h.authService.LogoutAll(c.Context(), principal); err != nil {
 		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusUnauthorized).JSON(res)
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
 	}
 
-	return c.JSON(&token)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AuthHandler) getSessions(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	sessions, err := h.authService.GetSessions(c.Context(), principal.Actor(), principal.SessionId)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.JSON(sessions)
+}
+
+func (h *AuthHandler) deleteSession(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	id := c.Params("id")
+	if err := h.authService.RevokeSession(c.Context(), principal.Actor(), id); err != nil {
+		return sessionError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
+}
+
+func (h *AuthHandler) getEmployeeSessions(c *fiber.Ctx) error {
+	employeeId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid employee id"})
+	}
+
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	subject := models.Actor{Type: models.ActorEmployee, Id: employeeId}
+	sessions, err := h.authService.GetSessions(c.Context(), subject, principal.SessionId)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.JSON(sessions)
+}
+
+func (h *AuthHandler) deleteEmployeeSession(c *fiber.Ctx) error {
+	employeeId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid employee id"})
+	}
+
+	id := c.Params("session_id")
+	subject := models.Actor{Type: models.ActorEmployee, Id: employeeId}
+	if err := h.authService.RevokeSession(c.Context(), subject, id); err != nil {
+		return sessionError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
+}
+
+func sessionError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+	if errors.Is(err, models.ErrSessionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(res)
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(res)
+}
+
+// loginError подбирает HTTP статус для ошибок входа по паролю
+func loginError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	var rateLimit *models.RateLimitError
+	switch {
+	case errors.As(err, &rateLimit):
+		setRetryAfter(c, rateLimit.RetryAfter)
+		return c.Status(fiber.StatusTooManyRequests).JSON(res)
+	case errors.Is(err, models.ErrEmailNotVerified), errors.Is(err, models.ErrEmployeeInactive):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	default:
+		return c.Status(fiber.StatusConflict).JSON(res)
+	}
+}
+
+// setRetryAfter заголовок Retry-After в целых секундах с округлением вверх
+func setRetryAfter(c *fiber.Ctx, retryAfter time.Duration) {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// newLoginAttemptStore хранилище попыток входа по настройке lockout.store
+func newLoginAttemptStore(db *sqlx.DB, cfg config.Lockout) models.LoginAttemptStore {
+	if cfg.Store == config.LockoutMemory {
+		return memory.NewLoginAttemptStore(cfg.Window, cfg.MaxDuration)
+	}
+	return repository.NewLoginAttemptRepository(db)
+}
+
+// sessionClient данные клиента для списка сессий
+func sessionClient(c *fiber.Ctx) models.SessionClient {
+	return models.SessionClient{UserAgent: c.Get(fiber.HeaderUserAgent), Ip: c.IP()}
 }
 
 func (h *AuthHandler) loginEmployee(c *fiber.Ctx) error {
@@ -72,25 +209,73 @@ func (h *AuthHandler) loginEmployee(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
 	}
 
-	token, err := h.authService.LoginEmployee(c.Context(), &employee)
+	token, challenge, err := h.authService.LoginEmployee(c.Context(), &employee, sessionClient(c))
 	if err != nil {
-		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusConflict).JSON(res)
+		return loginError(c, err)
 	}
 
+	if challenge != nil {
+		return c.JSON(challenge)
+	}
 	return c.JSON(token)
 }
 
 func AuthRouter(
+	db *sqlx.DB,
 	public fiber.Router,
 	protected fiber.Router,
 	userService models.UserRepository,
 	employeeService models.EmployeeRepository,
+	smsProvider sms.SMSProvider,
+	emailVerification *services.EmailVerificationService,
+	jwtConfig config.JWT,
+	otpConfig config.OTP,
+	mfaConfig config.MFA,
+	lockoutConfig config.Lockout,
+	timeout time.Duration,
 ) {
-	service := services.NewAuthService(employeeService, userService, 10*time.Second)
-	handler := NewAuthHander(service)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	otpService := services.NewOTPService(repository.NewOTPRepository(db), userService, smsProvider, otpConfig, timeout)
+	mfaService := services.NewMFAService(
+		repository.NewMFARepository(db),
+		employeeService,
+		repository.NewRoleRepository(db),
+		mfaConfig,
+		timeout,
+	)
+	service := services.NewAuthService(
+		employeeService,
+		userService,
+		refreshTokenRepo,
+		sessionRepo,
+		otpService,
+		emailVerification,
+		mfaService,
+		services.NewLoginGuard(newLoginAttemptStore(db, lockoutConfig), repository.NewAuditRepository(db), lockoutConfig),
+		jwtConfig,
+		timeout,
+	)
+	handler := NewAuthHander(service, otpService, mfaService)
 
 	public.Post("/users/signin", handler.loginUser)
 	public.Post("/employee/signin", handler.loginEmployee)
-	protected.Post("/auth/refresh", handler.refreshToken)
+	public.Post("/auth/refresh", handler.refreshToken)
+	public.Post("/auth/otp/send", handler.sendLoginCode)
+	public.Post("/auth/otp/signin", handler.loginUserOTP)
+	public.Post("/auth/mfa/verify", handler.verifyMFA)
+	public.Post("/auth/mfa/enroll", handler.enrollMFAChallenge)
+	protected.Post("/auth/logout", handler.logout)
+	protected.Post("/auth/logout-all", handler.logoutAll)
+	protected.Post("/auth/phone/send", handler.sendVerificationCode)
+	protected.Post("/auth/phone/verify", handler.verifyPhone)
+	protected.Get("/auth/mfa", handler.getMFAStatus)
+	protected.Post("/auth/mfa/totp", handler.enrollTOTP)
+	protected.Post("/auth/mfa/totp/confirm", handler.confirmTOTP)
+	protected.Post("/auth/mfa/totp/disable", handler.disableTOTP)
+	protected.Post("/auth/mfa/recovery-codes", handler.regenerateRecoveryCodes)
+	protected.Get("/auth/sessions", handler.getSessions)
+	protected.Delete("/auth/sessions/:id", handler.deleteSession)
+	protected.Get("/auth/employees/:id/sessions", middleware.RequireSuperRole(), handler.getEmployeeSessions)
+	protected.Delete("/auth/employees/:id/sessions/:session_id", middleware.RequireSuperRole(), handler.deleteEmployeeSession)
 }
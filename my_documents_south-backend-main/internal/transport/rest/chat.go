@@ -0,0 +1,277 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+// chatWriteTimeout время на отправку одного события клиенту по WebSocket
+const chatWriteTimeout = 10 * time.Second
+
+type ChatHandler struct {
+	chatService models.ChatService
+}
+
+func NewChatHandler(chatService models.ChatService) *ChatHandler {
+	return &ChatHandler{chatService: chatService}
+}
+
+// chatCommand сообщение от клиента по WebSocket
+type chatCommand struct {
+	Type models.ChatEventType `json:"type"`
+	Body string               `json:"body"`
+	UpTo int64                `json:"up_to"`
+}
+
+func (h *ChatHandler) getMessages(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	var cursor int64
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		if cursor, err = strconv.ParseInt(cursorStr, 10, 64); err != nil || cursor < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid cursor"})
+		}
+	}
+
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid limit"})
+	}
+
+	page, err := h.chatService.History(c.Context(), requestId, actor, cursor, limit)
+	if err != nil {
+		return chatError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func (h *ChatHandler) sendMessage(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	message, err := h.chatService.Send(c.Context(), requestId, actor, body.Body)
+	if err != nil {
+		return chatError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(message)
+}
+
+func (h *ChatHandler) markRead(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	var body struct {
+		UpTo int64 `json:"up_to"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.UpTo <= 0 {
+		res := models.NewErrorResponse(errors.New("up_to must be a positive message id"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	if err := h.chatService.MarkRead(c.Context(), requestId, actor, body.UpTo); err != nil {
+		return chatError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"up_to": body.UpTo})
+}
+
+// upgrade проверяет доступ к чату до установки WebSocket соединения,
+// чтобы клиент получил обычный HTTP статус ошибки
+func (h *ChatHandler) upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	if err := h.chatService.Authorize(c.Context(), requestId, actor); err != nil {
+		return chatError(c, err)
+	}
+
+	c.Locals("requestID", requestId)
+	c.Locals("actor", actor)
+	return c.Next()
+}
+
+func (h *ChatHandler) chat(conn *websocket.Conn) {
+	requestId := conn.Locals("requestID").(int64)
+	actor := conn.Locals("actor").(models.Actor)
+
+	subscription, err := h.chatService.Subscribe(context.Background(), requestId, actor)
+	if err != nil {
+		log.Printf("chat: failed to subscribe to request %d: %v", requestId, err)
+		return
+	}
+
+	// Запись в соединение выполняет только эта горутина
+	replies := make(chan models.ChatEvent, 1)
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for {
+			var event models.ChatEvent
+			select {
+			case e, ok := <-subscription.Events:
+				if !ok {
+					select {
+					case <-done:
+					default:
+						// подписка закрыта сервисом: участник потерял доступ, например при переназначении заявки
+						conn.SetWriteDeadline(time.Now().Add(chatWriteTimeout))
+						conn.WriteJSON(models.ChatEvent{Type: models.ChatEventError, RequestId: requestId, Error: models.ErrForbidden.Error()})
+						conn.Close()
+					}
+					return
+				}
+				// собственные события набора текста отправителю не нужны
+				if e.Type == models.ChatEventTyping && e.Actor != nil && *e.Actor == actor {
+					continue
+				}
+				event = e
+			case event = <-replies:
+			case <-done:
+				// ответ об ошибке перед закрытием соединения
+				select {
+				case event = <-replies:
+				default:
+					return
+				}
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(chatWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				// закрываем соединение, чтобы завершить цикл чтения
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		close(done)
+		subscription.Close()
+		<-writerDone
+	}()
+
+	for {
+		var command chatCommand
+		if err := conn.ReadJSON(&command); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("chat: read error for request %d: %v", requestId, err)
+			}
+			return
+		}
+
+		ctx := context.Background()
+		switch command.Type {
+		case models.ChatEventMessage:
+			_, err = h.chatService.Send(ctx, requestId, actor, command.Body)
+		case models.ChatEventTyping:
+			err = h.chatService.Typing(ctx, requestId, actor)
+		case models.ChatEventRead:
+			if command.UpTo <= 0 {
+				err = errors.New("up_to must be a positive message id")
+			} else {
+				err = h.chatService.MarkRead(ctx, requestId, actor, command.UpTo)
+			}
+		default:
+			err = errors.New("unknown event type")
+		}
+
+		if err != nil {
+			select {
+			case replies <- models.ChatEvent{Type: models.ChatEventError, RequestId: requestId, Error: err.Error()}:
+			default:
+			}
+			// участник мог потерять доступ, например при переназначении заявки
+			if errors.Is(err, models.ErrForbidden) {
+				return
+			}
+		}
+	}
+}
+
+// chatError подбирает HTTP статус для ошибок чата
+func chatError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	switch {
+	case errors.Is(err, models.ErrInvalidMessage):
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	case errors.Is(err, models.ErrForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(errors.New("request not found"), c.Path()))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
+func ChatRoute(db *sqlx.DB, ws fiber.Router, protected fiber.Router, timeout time.Duration) {
+	repo := repository.NewMessageRepository(db)
+	service := services.NewChatService(repo, timeout)
+	handler := NewChatHandler(service)
+
+	tag := protected.Group("/request/:id/messages")
+	tag.Get("", handler.getMessages)
+	tag.Post("", handler.sendMessage)
+	tag.Post("/read", handler.markRead)
+
+	ws.Get("/request/:id/chat", handler.upgrade, websocket.New(handler.chat))
+}
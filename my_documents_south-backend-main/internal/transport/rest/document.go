@@ -0,0 +1,170 @@
+package rest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/storage"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+type DocumentHandler struct {
+	documentService models.DocumentService
+}
+
+func NewDocumentHandler(documentService models.DocumentService) *DocumentHandler {
+	return &DocumentHandler{documentService: documentService}
+}
+
+func (h *DocumentHandler) uploadDocument(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		res := models.NewErrorResponse(errors.New("file is required"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+	defer file.Close()
+
+	document, err := h.documentService.Upload(c.Context(), requestId, models.DocumentUpload{
+		Name:    header.Filename,
+		Size:    header.Size,
+		Content: file,
+	}, actor)
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(document)
+}
+
+func (h *DocumentHandler) getDocuments(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	documents, err := h.documentService.GetByRequest(c.Context(), requestId)
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(documents)
+}
+
+func (h *DocumentHandler) downloadDocument(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("document_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid document id"})
+	}
+
+	document, content, err := h.documentService.Open(c.Context(), requestId, id)
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, document.MimeType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(document.Name)))
+	c.Set(fiber.HeaderETag, strconv.Quote(document.Checksum))
+	c.Set("X-Checksum-Sha256", document.Checksum)
+
+	// fasthttp закрывает content после отправки
+	return c.SendStream(content, int(document.Size))
+}
+
+func (h *DocumentHandler) deleteDocument(c *fiber.Ctx) error {
+	requestId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	id, err := strconv.ParseInt(c.Params("document_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid document id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	if err := h.documentService.Delete(c.Context(), requestId, id, actor); err != nil {
+		return documentError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
+}
+
+// documentError подбирает HTTP статус для ошибок работы с документами
+func documentError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	switch {
+	case errors.Is(err, models.ErrDocumentTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(res)
+	case errors.Is(err, models.ErrDocumentType):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(res)
+	case errors.Is(err, models.ErrDocumentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(res)
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(errors.New("request not found"), c.Path()))
+	case errors.Is(err, models.ErrForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, models.ErrDocumentInvalidName):
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
+func DocumentRoute(
+	db *sqlx.DB,
+	protected fiber.Router,
+	requestRepo models.RequestRepository,
+	store storage.BlobStore,
+	settings *services.SettingService,
+	maxUploadSize int64,
+	allowedMimeTypes []string,
+	timeout time.Duration,
+) {
+	repo := repository.NewDocumentRepository(db)
+	service := services.NewDocumentService(repo, requestRepo, store, settings, maxUploadSize, allowedMimeTypes, timeout)
+	handler := NewDocumentHandler(service)
+
+	access := requestAccess(requestRepo, timeout)
+
+	tag := protected.Group("/request/:id/documents")
+	tag.Post("", access, handler.uploadDocument)
+	tag.Get("", access, handler.getDocuments)
+	tag.Get("/:document_id", access, handler.downloadDocument)
+	tag.Delete("/:document_id", access, handler.deleteDocument)
+}
@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailVerificationHandler struct {
+	emailVerificationService *services.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(emailVerificationService *services.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{emailVerificationService: emailVerificationService}
+}
+
+func (h *EmailVerificationHandler) verifyEmail(c *fiber.Ctx) error {
+	if err := h.emailVerificationService.Verify(c.Context(), c.Query("token")); err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		if errors.Is(err, models.ErrInvalidVerificationToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.JSON(fiber.Map{"status": "success", "message": "Email verified"})
+}
+
+func (h *EmailVerificationHandler) resend(c *fiber.Ctx) error {
+	var body struct {
+		Phone string `json:"phone"`
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+	if (body.Phone == "") == (body.Email == "") {
+		res := models.NewErrorResponse(errors.New("either phone or email is required"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	if err := h.emailVerificationService.Resend(c.Context(), body.Phone, body.Email); err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// emailVerified пропускает запрос, только если политика email_verification.policy
+// не требует подтверждённой почты или почта инициатора подтверждена
+func emailVerified(service *services.EmailVerificationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actor, err := actorFromCtx(c)
+		if err != nil {
+			res := models.NewErrorResponse(err, c.Path()).Log()
+			return c.Status(fiber.StatusUnauthorized).JSON(res)
+		}
+
+		if err := service.CheckRequest(c.Context(), actor); err != nil {
+			res := models.NewErrorResponse(err, c.Path()).Log()
+			if errors.Is(err, models.ErrEmailNotVerified) {
+				return c.Status(fiber.StatusForbidden).JSON(res)
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(res)
+		}
+
+		return c.Next()
+	}
+}
+
+func EmailVerificationRoute(public fiber.Router, service *services.EmailVerificationService) {
+	handler := NewEmailVerificationHandler(service)
+
+	public.Get("/auth/verify-email", handler.verifyEmail)
+	public.Post("/auth/verify-email/resend", handler.resend)
+}
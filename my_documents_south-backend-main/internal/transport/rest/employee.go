@@ -2,9 +2,12 @@ package rest
 
 import (
 	"errors"
+	"log"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/utils/password"
 	"strconv"
 	"time"
 
@@ -13,14 +16,26 @@ import (
 )
 
 type EmployeeHandler struct {
-	employeeService models.EmployeeService
+	employeeService   models.EmployeeService
+	bootstrapService  *services.BootstrapService
+	emailVerification *services.EmailVerificationService
 }
 
-func NewEmployeeHandler(employeeService models.EmployeeService) *EmployeeHandler {
-	return &EmployeeHandler{employeeService: employeeService}
+func NewEmployeeHandler(
+	employeeService models.EmployeeService,
+	bootstrapService *services.BootstrapService,
+	emailVerification *services.EmailVerificationService,
+) *EmployeeHandler {
+	return &EmployeeHandler{
+		employeeService:   employeeService,
+		bootstrapService:  bootstrapService,
+		emailVerification: emailVerification,
+	}
 }
 
-func (h *EmployeeHandler) createEmployee(c *fiber.Ctx) error {
+// bootstrapEmployee создаёт первого сотрудника с суперролью, а также суперроль и тариф по умолчанию,
+// если они не заданы. Остальные сотрудники добавляются по приглашениям
+func (h *EmployeeHandler) bootstrapEmployee(c *fiber.Ctx) error {
 	var employee models.Employee
 
 	if err := c.BodyParser(&employee); err != nil {
@@ -28,16 +43,53 @@ func (h *EmployeeHandler) createEmployee(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
 	}
 
-	err := h.employeeService.Create(c.Context(), &employee)
+	_, err := h.bootstrapService.Run(c.Context(), &models.Bootstrap{Admin: &employee, Strict: true})
 	if err != nil {
 		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusConflict).JSON(res)
+		if errors.Is(err, models.ErrBootstrapClosed) {
+			return c.Status(fiber.StatusForbidden).JSON(res)
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
 	}
 
-	return c.SendStatus(fiber.StatusCreated)
+	// аккаунт уже создан: при ошибке письмо можно запросить повторно
+	subject := models.Actor{Type: models.ActorEmployee, Id: employee.Id}
+	if err := h.emailVerification.Send(c.Context(), subject, employee.Email); err != nil {
+		log.Printf("employee %d: failed to send email verification: %v", employee.Id, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": employee.Id, "role_id": employee.RoleId})
 }
+
+// getEmployee список сотрудников с фильтрами role_id, service_id и active
 func (h *EmployeeHandler) getEmployee(c *fiber.Ctx) error {
-	employees, err := h.employeeService.GetAllWithServices(c.Context())
+	var filter models.EmployeeFilter
+	if value := c.Query("role_id"); value != "" {
+		roleId, err := strconv.Atoi(value)
+		if err != nil {
+			res := models.NewErrorResponse(errors.New("invalid role_id"), c.Path()).Log()
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		}
+		filter.RoleId = &roleId
+	}
+	if value := c.Query("service_id"); value != "" {
+		serviceId, err := strconv.Atoi(value)
+		if err != nil {
+			res := models.NewErrorResponse(errors.New("invalid service_id"), c.Path()).Log()
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		}
+		filter.ServiceId = &serviceId
+	}
+	if value := c.Query("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			res := models.NewErrorResponse(errors.New("invalid active"), c.Path()).Log()
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		}
+		filter.Active = &active
+	}
+
+	employees, err := h.employeeService.GetAllWithServices(c.Context(), filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -52,32 +104,117 @@ func (h *EmployeeHandler) getEmployeeById(c *fiber.Ctx) error {
 
 	employee, err := h.employeeService.GetByIdWithServices(c.Context(), id)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "employee not found"})
+		return employeeError(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(employee)
 }
 
 func (h *EmployeeHandler) deleteEmployee(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		res := models.NewErrorResponse(errors.New("invalid id"), c.Path()).Log()
 		return c.Status(fiber.StatusBadRequest).JSON(res)
 	}
 
-	err = h.employeeService.Delete(c.Context(), id)
+	principal, err := principalFromCtx(c)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err.Error() == "user not found" {
-			status = fiber.StatusNotFound
-		}
-		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(status).JSON(res)
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	err = h.employeeService.Delete(c.Context(), principal, id)
+	if err != nil {
+		return employeeError(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
 }
 
+// patchEmployee меняет переданные поля сотрудника. При смене почты отправляется письмо для её подтверждения
+func (h *EmployeeHandler) patchEmployee(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		res := models.NewErrorResponse(errors.New("invalid employee id"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	var update models.EmployeeUpdate
+	if err := c.BodyParser(&update); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	employee, err := h.employeeService.Patch(c.Context(), principal, id, update)
+	if err != nil {
+		return employeeError(c, err)
+	}
+
+	if employee.EmailVerifiedAt == nil && update.Email != nil {
+		subject := models.Actor{Type: models.ActorEmployee, Id: employee.Id}
+		if err := h.emailVerification.Send(c.Context(), subject, employee.Email); err != nil {
+			log.Printf("employee %d: failed to send email verification: %v", employee.Id, err)
+		}
+	}
+
+	return c.JSON(employee)
+}
+
+func (h *EmployeeHandler) deactivateEmployee(c *fiber.Ctx) error {
+	return h.setActive(c, false)
+}
+
+func (h *EmployeeHandler) reactivateEmployee(c *fiber.Ctx) error {
+	return h.setActive(c, true)
+}
+
+func (h *EmployeeHandler) setActive(c *fiber.Ctx, active bool) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		res := models.NewErrorResponse(errors.New("invalid employee id"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	employee, err := h.employeeService.SetActive(c.Context(), principal, id, active)
+	if err != nil {
+		return employeeError(c, err)
+	}
+
+	return c.JSON(employee)
+}
+
+// changePassword смена пароля самим сотрудником
+func (h *EmployeeHandler) changePassword(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.employeeService.ChangePassword(c.Context(), principal, body.CurrentPassword, body.NewPassword); err != nil {
+		return employeeError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (h *EmployeeHandler) addService(c *fiber.Ctx) error {
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
@@ -96,7 +233,7 @@ func (h *EmployeeHandler) addService(c *fiber.Ctx) error {
 	}
 
 	if err := h.employeeService.AddService(c.Context(), id, body.ServiceId); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return employeeError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusCreated)
@@ -113,24 +250,54 @@ func (h *EmployeeHandler) removeService(c *fiber.Ctx) error {
 	}
 
 	if err := h.employeeService.RemoveService(c.Context(), id, serviceId); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return employeeError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
 
-func EmployeeRoute(db *sqlx.DB, public fiber.Router, protected fiber.Router, roleRepo models.RoleRepository) models.EmployeeRepository {
+// employeeError подбирает HTTP статус для ошибок управления сотрудниками
+func employeeError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	switch {
+	case errors.Is(err, models.ErrInvalidEmployee), errors.Is(err, password.ErrInvalidPassword):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	case errors.Is(err, models.ErrEmployeeNotFound), errors.Is(err, models.ErrEmployeeServiceNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(res)
+	case errors.Is(err, models.ErrForbidden), errors.Is(err, models.ErrWrongPassword):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, models.ErrEmployeeExists):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
+func EmployeeRoute(
+	db *sqlx.DB,
+	public fiber.Router,
+	protected fiber.Router,
+	roleService models.RoleService,
+	emailVerification *services.EmailVerificationService,
+	timeout time.Duration,
+) models.EmployeeRepository {
 	repo := repository.NewEmployeeRepository(db)
-	service := services.NewEmployeeService(repo, roleRepo, 10*time.Second)
-	handler := NewEmployeeHandler(service)
+	service := services.NewEmployeeService(repo, roleService, repository.NewSessionRepository(db), timeout)
+	bootstrapService := services.NewBootstrapService(repository.NewBootstrapRepository(db), timeout)
+	handler := NewEmployeeHandler(service, bootstrapService, emailVerification)
 
 	// OPEN /pub
-	public.Post("/employee/signup", handler.createEmployee)
+	public.Post("/employee/bootstrap", handler.bootstrapEmployee)
 	// ONLY WITH JWT /prot
-	protected.Get("/employee", handler.getEmployee)
-	protected.Get("/employee/:id", handler.getEmployeeById)
-	protected.Delete("/employee/:id", handler.deleteEmployee)
-	protected.Post("/employee/:id/service", handler.addService)
-	protected.Delete("/employee/:id/service/:service_id", handler.removeService)
+	protected.Post("/employee/me/password", handler.changePassword)
+	protected.Get("/employee", middleware.Require(models.PermEmployeeRead), handler.getEmployee)
+	protected.Get("/employee/:id", middleware.Require(models.PermEmployeeRead), handler.getEmployeeById)
+	protected.Delete("/employee/:id", middleware.Require(models.PermEmployeeWrite), handler.deleteEmployee)
+	protected.Patch("/employee/:id", middleware.Require(models.PermEmployeeWrite), handler.patchEmployee)
+	protected.Post("/employee/:id/deactivate", middleware.Require(models.PermEmployeeWrite), handler.deactivateEmployee)
+	protected.Post("/employee/:id/reactivate", middleware.Require(models.PermEmployeeWrite), handler.reactivateEmployee)
+	protected.Post("/employee/:id/service", middleware.Require(models.PermEmployeeWrite), handler.addService)
+	protected.Delete("/employee/:id/service/:service_id", middleware.Require(models.PermEmployeeWrite), handler.removeService)
 	return repo
 }
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
+	"my_documents_south_backend/internal/middleware"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/utils/password"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+type InviteHandler struct {
+	inviteService *services.InviteService
+}
+
+func NewInviteHandler(inviteService *services.InviteService) *InviteHandler {
+	return &InviteHandler{inviteService: inviteService}
+}
+
+func (h *InviteHandler) createInvite(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	var invite models.EmployeeInvite
+	if err := c.BodyParser(&invite); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.inviteService.Create(c.Context(), principal, &invite); err != nil {
+		return inviteError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(invite)
+}
+
+func (h *InviteHandler) getInvites(c *fiber.Ctx) error {
+	invites, err := h.inviteService.GetPending(c.Context())
+	if err != nil {
+		return inviteError(c, err)
+	}
+
+	return c.JSON(invites)
+}
+
+func (h *InviteHandler) revokeInvite(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid invite id"})
+	}
+
+	if err := h.inviteService.Revoke(c.Context(), id); err != nil {
+		return inviteError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
+}
+
+func (h *InviteHandler) getInvite(c *fiber.Ctx) error {
+	invite, err := h.inviteService.GetByToken(c.Context(), c.Query("token"))
+	if err != nil {
+		return inviteError(c, err)
+	}
+
+	return c.JSON(invite)
+}
+
+func (h *InviteHandler) acceptInvite(c *fiber.Ctx) error {
+	var body struct {
+		Token      string `json:"token"`
+		Name       string `json:"name"`
+		LastName   string `json:"last_name"`
+		MiddleName string `json:"middle_name"`
+		Password   string `json:"password"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	employee := models.Employee{
+		Name:       body.Name,
+		LastName:   body.LastName,
+		MiddleName: body.MiddleName,
+		Password:   body.Password,
+	}
+	if err := h.inviteService.Accept(c.Context(), body.Token, &employee); err != nil {
+		return inviteError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": employee.Id})
+}
+
+// inviteError подбирает HTTP статус для ошибок приглашений
+func inviteError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	switch {
+	case errors.Is(err, models.ErrInvalidInvite), errors.Is(err, password.ErrInvalidPassword):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	case errors.Is(err, models.ErrInvalidInviteToken), errors.Is(err, models.ErrInviteNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(res)
+	case errors.Is(err, models.ErrForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, models.ErrEmployeeExists):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
+func InviteRoute(
+	db *sqlx.DB,
+	public fiber.Router,
+	protected fiber.Router,
+	roleService models.RoleService,
+	mail mailer.Mailer,
+	inviteConfig config.Invite,
+	timeout time.Duration,
+) {
+	service := services.NewInviteService(repository.NewInviteRepository(db), roleService, mail, inviteConfig, timeout)
+	handler := NewInviteHandler(service)
+
+	public.Get("/invites", handler.getInvite)
+	public.Post("/invites/accept", handler.acceptInvite)
+	protected.Get("/invites", middleware.Require(models.PermEmployeeInvite), handler.getInvites)
+	protected.Post("/invites", middleware.Require(models.PermEmployeeInvite), handler.createInvite)
+	protected.Delete("/invites/:id", middleware.Require(models.PermEmployeeInvite), handler.revokeInvite)
+}
@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type mfaCodeBody struct {
+	Code string `json:"code"`
+}
+
+func (h *AuthHandler) verifyMFA(c *fiber.Ctx) error {
+	var body struct {
+		Token string `json:"mfa_token"`
+		Code  string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	result, err := h.authService.VerifyMFA(c.Context(), body.Token, body.Code, sessionClient(c))
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(result)
+}
+
+func (h *AuthHandler) enrollMFAChallenge(c *fiber.Ctx) error {
+	var body struct {
+		Token string `json:"mfa_token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	enrollment, err := h.mfaService.EnrollChallenge(c.Context(), body.Token)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(enrollment)
+}
+
+func (h *AuthHandler) getMFAStatus(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	status, err := h.mfaService.Status(c.Context(), principal.Id, *principal.RoleId)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(status)
+}
+
+func (h *AuthHandler) enrollTOTP(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := h.mfaService.Enroll(c.Context(), principal.Id)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(enrollment)
+}
+
+func (h *AuthHandler) confirmTOTP(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var body mfaCodeBody
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	codes, err := h.mfaService.Confirm(c.Context(), principal.Id, body.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+func (h *AuthHandler) disableTOTP(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var body mfaCodeBody
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.mfaService.Disable(c.Context(), principal.Id, *principal.RoleId, body.Code); err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AuthHandler) regenerateRecoveryCodes(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var body mfaCodeBody
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Context(), principal.Id, body.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// employeePrincipal двухфакторная аутентификация доступна только сотрудникам.
+// Если запрос сделал не сотрудник, ответ уже отправлен и возвращается его ошибка
+func employeePrincipal(c *fiber.Ctx) (*models.Principal, error) {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+	if !principal.IsEmployee() || principal.RoleId == nil {
+		res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+		return nil, c.Status(fiber.StatusForbidden).JSON(res)
+	}
+	return principal, nil
+}
+
+// mfaError подбирает HTTP статус для ошибок двухфакторной аутентификации
+func mfaError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	var rateLimit *models.RateLimitError
+	switch {
+	case errors.As(err, &rateLimit):
+		setRetryAfter(c, rateLimit.RetryAfter)
+		return c.Status(fiber.StatusTooManyRequests).JSON(res)
+	case errors.Is(err, models.ErrInvalidMFAChallenge), errors.Is(err, models.ErrInvalidMFACode):
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	case errors.Is(err, models.ErrMFARequired), errors.Is(err, models.ErrEmployeeInactive):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, models.ErrMFAAlreadyEnabled), errors.Is(err, models.ErrMFANotEnabled), errors.Is(err, models.ErrMFAEnrollmentRequired):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *AuthHandler) sendLoginCode(c *fiber.Ctx) error {
+	var body struct {
+		Phone string `json:"phone"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.otpService.SendLogin(c.Context(), body.Phone); err != nil {
+		return otpError(c, err, fiber.StatusBadRequest)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (h *AuthHandler) loginUserOTP(c *fiber.Ctx) error {
+	var body struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	token, err := h.authService.LoginUserOTP(c.Context(), body.Phone, body.Code, sessionClient(c))
+	if err != nil {
+		return otpError(c, err, fiber.StatusUnauthorized)
+	}
+
+	return c.JSON(token)
+}
+
+func (h *AuthHandler) sendVerificationCode(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+	if principal.IsEmployee() {
+		res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	}
+
+	if err := h.otpService.SendVerification(c.Context(), principal.Id); err != nil {
+		return otpError(c, err, fiber.StatusBadRequest)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (h *AuthHandler) verifyPhone(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+	if principal.IsEmployee() {
+		res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.otpService.ConfirmPhone(c.Context(), principal.Id, body.Code); err != nil {
+		return otpError(c, err, fiber.StatusBadRequest)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// otpError подбирает HTTP статус для ошибок SMS кодов. invalidCodeStatus — статус для неверного кода
+func otpError(c *fiber.Ctx, err error, invalidCodeStatus int) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	var rateLimit *models.RateLimitError
+	switch {
+	case errors.As(err, &rateLimit):
+		setRetryAfter(c, rateLimit.RetryAfter)
+		return c.Status(fiber.StatusTooManyRequests).JSON(res)
+	case errors.Is(err, models.ErrInvalidOTP), errors.Is(err, models.ErrOTPAttemptsExceeded):
+		return c.Status(invalidCodeStatus).JSON(res)
+	case errors.Is(err, models.ErrInvalidPhone):
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	case errors.Is(err, models.ErrEmailNotVerified):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, models.ErrPhoneVerified):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/utils/password"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService *services.PasswordResetService
+}
+
+func NewPasswordResetHandler(passwordResetService *services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{passwordResetService: passwordResetService}
+}
+
+func (h *PasswordResetHandler) forgot(c *fiber.Ctx) error {
+	var body struct {
+		Phone string `json:"phone"`
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+	if (body.Phone == "") == (body.Email == "") {
+		res := models.NewErrorResponse(errors.New("either phone or email is required"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	if err := h.passwordResetService.Forgot(c.Context(), body.Phone, body.Email); err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (h *PasswordResetHandler) reset(c *fiber.Ctx) error {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.passwordResetService.Reset(c.Context(), body.Token, body.Password); err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		if errors.Is(err, models.ErrInvalidResetToken) || errors.Is(err, password.ErrInvalidPassword) {
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func PasswordResetRoute(
+	db *sqlx.DB,
+	public fiber.Router,
+	userRepository models.UserRepository,
+	employeeRepository models.EmployeeRepository,
+	mail mailer.Mailer,
+	cfg config.PasswordReset,
+	timeout time.Duration,
+) {
+	service := services.NewPasswordResetService(
+		repository.NewPasswordResetRepository(db),
+		userRepository,
+		employeeRepository,
+		repository.NewSessionRepository(db),
+		mail,
+		cfg,
+		timeout,
+	)
+	handler := NewPasswordResetHandler(service)
+
+	public.Post("/auth/password/forgot", handler.forgot)
+	public.Post("/auth/password/reset", handler.reset)
+}
@@ -1,11 +1,14 @@
 package rest
 
 import (
+	"database/sql"
 	"errors"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -28,8 +31,37 @@ func (h *RequestHandler) createRequest(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
 	}
 
-	err := h.requestService.Create(c.Context(), &req)
+	principal, err := principalFromCtx(c)
 	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	// владелец заявки всегда клиент: клиент создаёт заявку только от своего имени,
+	// сотрудник с правом request.create — от имени клиента из owner_id
+	if principal.IsEmployee() {
+		if !principal.Can(models.PermRequestCreate) {
+			res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+			return c.Status(fiber.StatusForbidden).JSON(res)
+		}
+		createdBy := principal.Id
+		req.CreatedByEmployeeId = &createdBy
+		if !principal.Can(models.PermRequestAssign) {
+			req.EmployeeId = 0
+		}
+	} else {
+		req.OwnerId = principal.Id
+		req.CreatedByEmployeeId = nil
+		req.EmployeeId = 0
+	}
+
+	err = h.requestService.Create(c.Context(), &req)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidOwner) ||
+			errors.Is(err, models.ErrInvalidRequest) ||
+			errors.Is(err, models.ErrInvalidPriority) {
+			return requestError(c, err)
+		}
 		res := models.NewErrorResponse(err, c.Path()).Log()
 		return c.Status(fiber.StatusConflict).JSON(res)
 	}
@@ -50,58 +82,204 @@ func (h *RequestHandler) getRequestById(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(res)
 	}
 
+	c.Set(fiber.HeaderETag, requestETag(user))
 	return c.Status(fiber.StatusOK).JSON(user)
 }
 
+var errIfMatchRequired = errors.New("if-match header with request version is required")
+
+func (h *RequestHandler) patchRequest(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	var update models.RequestUpdate
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	if update.Priority != nil && !principal.Can(models.PermRequestUpdatePriority) {
+		res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	}
+
+	// без If-Match два оператора могли бы незаметно перезаписать изменения друг друга
+	version, err := ifMatchVersion(c.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusPreconditionRequired).JSON(res)
+	}
+
+	req, err := h.requestService.Patch(c.Context(), id, version, update, principal.Actor())
+	if err != nil {
+		return requestError(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, requestETag(req))
+	return c.Status(fiber.StatusOK).JSON(req)
+}
+
+// requestETag версия заявки в формате ETag
+func requestETag(req *models.Request) string {
+	return `"` + strconv.FormatInt(int64(req.Version), 10) + `"`
+}
+
+// ifMatchVersion извлекает версию заявки из If-Match. "*" соответствует любой версии и возвращает 0
+func ifMatchVersion(header string) (int32, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return 0, nil
+	}
+
+	// кавычки снимаются только парой, "1 или ""1"" не являются ETag
+	header = strings.TrimPrefix(header, "W/")
+	if len(header) >= 2 && strings.HasPrefix(header, `"`) && strings.HasSuffix(header, `"`) {
+		header = header[1 : len(header)-1]
+	}
+	version, err := strconv.ParseInt(header, 10, 32)
+	if err != nil || version < 1 {
+		return 0, errIfMatchRequired
+	}
+	return int32(version), nil
+}
+
 func (h *RequestHandler) getRequestsWithFilter(c *fiber.Ctx) error {
-	filter := models.Request{}
+	filter := models.RequestFilter{}
+	invalid := func(name string) error {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid " + name})
+	}
+
+	var err error
+	if filter.OwnerIds, err = queryIds(c, "owner_id"); err != nil {
+		return invalid("owner_id")
+	}
+	if filter.EmployeeIds, err = queryIds(c, "employee_id"); err != nil {
+		return invalid("employee_id")
+	}
+	serviceIds, err := queryIds(c, "service_id")
+	if err != nil {
+		return invalid("service_id")
+	}
+	for _, id := range serviceIds {
+		filter.ServiceIds = append(filter.ServiceIds, int(id))
+	}
 
-	if ownerIdStr := c.Query("owner_id"); ownerIdStr != "" {
-		if ownerId, err := strconv.ParseInt(ownerIdStr, 10, 64); err == nil {
-			filter.OwnerId = ownerId
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid owner_id"})
+	if value := c.Query("status"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			status, err := models.ParseRequestStatus(strings.TrimSpace(item))
+			if err != nil {
+				return invalid("status")
+			}
+			filter.Statuses = append(filter.Statuses, status)
 		}
 	}
-	if serviceIdStr := c.Query("service_id"); serviceIdStr != "" {
-		if serviceId, err := strconv.ParseInt(serviceIdStr, 10, 32); err == nil {
-			filter.ServiceId = int(serviceId)
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid service_id"})
+
+	if value := c.Query("unassigned"); value != "" {
+		if filter.Unassigned, err = strconv.ParseBool(value); err != nil {
+			return invalid("unassigned")
 		}
 	}
 
-	if statusStr := c.Query("status"); statusStr != "" {
-		if status, err := strconv.ParseInt(statusStr, 10, 16); err == nil {
-			filter.Status = int16(status)
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid status"})
+	for name, target := range map[string]**int16{"priority_min": &filter.PriorityMin, "priority_max": &filter.PriorityMax} {
+		if value := c.Query(name); value != "" {
+			priority, err := strconv.ParseInt(value, 10, 16)
+			if err != nil {
+				return invalid(name)
+			}
+			value := int16(priority)
+			*target = &value
 		}
 	}
 
-	if employeeIdStr := c.Query("employee_id"); employeeIdStr != "" {
-		if employeeId, err := strconv.ParseInt(employeeIdStr, 10, 64); err == nil {
-			filter.EmployeeId = employeeId
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid employee_id"})
+	// desired_at оставлен для совместимости и означает desired_to, поэтому разбирается раньше него
+	times := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+		{"desired_from", &filter.DesiredFrom},
+		{"desired_at", &filter.DesiredTo},
+		{"desired_to", &filter.DesiredTo},
+	}
+	for _, param := range times {
+		if value := c.Query(param.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return invalid(param.name)
+			}
+			*param.target = &t
 		}
 	}
 
-	if desiredAt := c.Query("desired_at"); desiredAt != "" {
-		if t, err := time.Parse(time.RFC3339, desiredAt); err == nil {
-			filter.DesiredAt = t
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid desired_at"})
+	if filter.Sort, err = models.ParseRequestSort(c.Query("sort")); err != nil {
+		return requestError(c, err)
+	}
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+			return invalid("limit")
+		}
+	}
+	if value := c.Query("cursor"); value != "" {
+		if filter.Cursor, err = models.DecodeRequestCursor(value, filter.Sort); err != nil {
+			return requestError(c, err)
 		}
 	}
 
-	requests, err := h.requestService.GetWithFilter(c.Context(), filter)
+	principal, err := principalFromCtx(c)
 	if err != nil {
 		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusInternalServerError).JSON(res)
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	// клиент видит только свои заявки, сотрудник без request.read_all — только назначенные ему
+	switch {
+	case !principal.IsEmployee():
+		if len(filter.OwnerIds) > 1 || len(filter.OwnerIds) == 1 && filter.OwnerIds[0] != principal.Id {
+			res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+			return c.Status(fiber.StatusForbidden).JSON(res)
+		}
+		filter.OwnerIds = []int64{principal.Id}
+	case !principal.Can(models.PermRequestReadAll):
+		if filter.Unassigned || len(filter.EmployeeIds) > 1 || len(filter.EmployeeIds) == 1 && filter.EmployeeIds[0] != principal.Id {
+			res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+			return c.Status(fiber.StatusForbidden).JSON(res)
+		}
+		filter.EmployeeIds = []int64{principal.Id}
+	}
+
+	page, err := h.requestService.GetWithFilter(c.Context(), filter)
+	if err != nil {
+		return requestError(c, err)
+	}
+
+	c.Set("X-Total-Count", strconv.Itoa(page.Total))
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// queryIds разбирает список id через запятую из параметра name
+func queryIds(c *fiber.Ctx, name string) ([]int64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
 	}
 
-	return c.Status(fiber.StatusOK).JSON(requests)
+	var ids []int64
+	for _, item := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+		if err != nil || id < 1 {
+			return nil, errors.New("invalid id")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 func (h *RequestHandler) updateRequestEmployee(c *fiber.Ctx) error {
@@ -110,6 +288,12 @@ func (h *RequestHandler) updateRequestEmployee(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
 	}
 
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
 	type payload struct {
 		EmployeeId int64 `json:"employee_id"`
 	}
@@ -123,22 +307,102 @@ func (h *RequestHandler) updateRequestEmployee(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "employee_id is required"})
 	}
 
-	if err := h.requestService.UpdateEmployee(c.Context(), id, body.EmployeeId); err != nil {
+	if err := h.requestService.UpdateEmployee(c.Context(), id, body.EmployeeId, actor); err != nil {
+		return requestError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *RequestHandler) updateRequestPriority(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
 		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusInternalServerError).JSON(res)
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	type payload struct {
+		Priority *int16 `json:"priority"`
+	}
+
+	var body payload
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	if body.Priority == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "priority is required"})
+	}
+
+	if err := h.requestService.UpdatePriority(c.Context(), id, *body.Priority, actor); err != nil {
+		return requestError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
 
+func (h *RequestHandler) addRequestComment(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	type payload struct {
+		Comment string `json:"comment"`
+	}
+
+	var body payload
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	event, err := h.requestService.AddComment(c.Context(), id, body.Comment, actor)
+	if err != nil {
+		return requestError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(event)
+}
+
+func (h *RequestHandler) getRequestHistory(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	events, err := h.requestService.GetHistory(c.Context(), id)
+	if err != nil {
+		return requestError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(events)
+}
+
 func (h *RequestHandler) updateRequestStatus(c *fiber.Ctx) error {
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
 	}
 
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
 	type payload struct {
-		Status int16 `json:"status"`
+		Status models.RequestStatus `json:"status"`
 	}
 
 	var body payload
@@ -150,14 +414,77 @@ func (h *RequestHandler) updateRequestStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status is required"})
 	}
 
-	if err := h.requestService.UpdateStatus(c.Context(), id, body.Status); err != nil {
-		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	// клиент может только отменить свою заявку, сотруднику нужно право на смену статуса
+	if actor.IsEmployee() && !middleware.HasPermission(c, models.PermRequestUpdateStatus) {
+		res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	}
+
+	if err := h.requestService.UpdateStatus(c.Context(), id, body.Status, actor); err != nil {
+		return requestError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
 
+func (h *RequestHandler) getRequestTransitions(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request id"})
+	}
+
+	actor, err := actorFromCtx(c)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusUnauthorized).JSON(res)
+	}
+
+	// без права на смену статуса сотруднику не доступен ни один переход, как в updateRequestStatus
+	if actor.IsEmployee() && !middleware.HasPermission(c, models.PermRequestUpdateStatus) {
+		return c.Status(fiber.StatusOK).JSON(models.NewStatusInfos(nil))
+	}
+
+	statuses, err := h.requestService.Transitions(c.Context(), id, actor)
+	if err != nil {
+		return requestError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.NewStatusInfos(statuses))
+}
+
+// requestError подбирает HTTP статус для ошибок изменения заявки
+func requestError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		return c.Status(fiber.StatusConflict).JSON(models.TransitionErrorResponse{
+			ErrorResponse: res,
+			Allowed:       models.NewStatusInfos(transitionErr.Allowed),
+		})
+	case errors.Is(err, models.ErrStatusChanged):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	case errors.Is(err, models.ErrRequestModified):
+		return c.Status(fiber.StatusPreconditionFailed).JSON(res)
+	case errors.Is(err, models.ErrRequestClosed):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	case errors.Is(err, models.ErrInvalidStatus),
+		errors.Is(err, models.ErrInvalidPriority),
+		errors.Is(err, models.ErrInvalidComment),
+		errors.Is(err, models.ErrInvalidRequest),
+		errors.Is(err, models.ErrInvalidFilter),
+		errors.Is(err, models.ErrInvalidCursor):
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	case errors.Is(err, models.ErrInvalidOwner), errors.Is(err, models.ErrInvalidAssignee):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(models.NewErrorResponse(errors.New("request not found"), c.Path()))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
 func (h *RequestHandler) deleteRequest(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
@@ -174,17 +501,34 @@ func (h *RequestHandler) deleteRequest(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
 }
 
-func RequestRoute(db *sqlx.DB, protected fiber.Router, user models.UserRepository, employee models.EmployeeRepository) {
+func RequestRoute(
+	db *sqlx.DB,
+	protected fiber.Router,
+	user models.UserRepository,
+	employee models.EmployeeRepository,
+	emailVerification *services.EmailVerificationService,
+	settings *services.SettingService,
+	timeout time.Duration,
+) models.RequestRepository {
 	repo := repository.NewRequestRepository(db)
-	service := services.NewRequestService(repo, user, employee, 10*time.Second)
+	service := services.NewRequestService(repo, user, employee, settings, timeout)
 
 	handler := NewRequestHandler(service)
 
+	access := requestAccess(repo, timeout)
+
 	tag := protected.Group("/request")
-	tag.Post("", handler.createRequest)
+	tag.Post("", emailVerified(emailVerification), handler.createRequest)
 	tag.Get("", handler.getRequestsWithFilter)
-	tag.Get("/:id", handler.getRequestById)
-	tag.Patch("/:id/employee", handler.updateRequestEmployee)
-	tag.Patch("/:id/status", handler.updateRequestStatus)
-	tag.Delete("/:id", handler.deleteRequest)
+	tag.Get("/:id", access, handler.getRequestById)
+	tag.Patch("/:id", access, handler.patchRequest)
+	tag.Patch("/:id/employee", middleware.Require(models.PermRequestAssign), access, handler.updateRequestEmployee)
+	tag.Patch("/:id/status", access, handler.updateRequestStatus)
+	tag.Get("/:id/transitions", access, handler.getRequestTransitions)
+	tag.Patch("/:id/priority", middleware.Require(models.PermRequestUpdatePriority), access, handler.updateRequestPriority)
+	tag.Post("/:id/comments", access, handler.addRequestComment)
+	tag.Get("/:id/history", access, handler.getRequestHistory)
+	tag.Delete("/:id", middleware.Require(models.PermRequestDelete), handler.deleteRequest)
+
+	return repo
 }
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int32
+		wantErr bool
+	}{
+		{"strong etag", `"5"`, 5, false},
+		{"weak etag", `W/"12"`, 12, false},
+		{"unquoted version", `7`, 7, false},
+		{"surrounding spaces", ` "3" `, 3, false},
+		{"any version", `*`, 0, false},
+		{"any version with spaces", ` * `, 0, false},
+		{"empty header", ``, 0, true},
+		{"empty etag", `""`, 0, true},
+		{"zero version", `"0"`, 0, true},
+		{"negative version", `"-1"`, 0, true},
+		{"opening quote only", `"5`, 0, true},
+		{"closing quote only", `5"`, 0, true},
+		{"double quotes", `""5""`, 0, true},
+		{"not a number", `"abc"`, 0, true},
+		{"list of etags", `"1", "2"`, 0, true},
+		{"overflow", `"2147483648"`, 0, true},
+		{"weak prefix without etag", `W/`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ifMatchVersion(tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, errIfMatchRequired) {
+					t.Errorf("ifMatchVersion(%q) error = %v, want errIfMatchRequired", tt.header, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ifMatchVersion(%q) = (%d, %v), want %d", tt.header, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestETagRoundTrip(t *testing.T) {
+	for _, version := range []int32{1, 42, 1<<31 - 1} {
+		etag := requestETag(&models.Request{Version: version})
+		got, err := ifMatchVersion(etag)
+		if err != nil || got != version {
+			t.Errorf("ifMatchVersion(%s) = (%d, %v), want %d", etag, got, err, version)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"database/sql"
 	"errors"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
 	"time"
@@ -21,8 +23,8 @@ func NewRoleHandler(roleService models.RoleService) *RoleHandler {
 }
 
 func (h *RoleHandler) createRole(c *fiber.Ctx) error {
-	_, ok := c.Locals("roleID").(int)
-	if !ok {
+	principal, ok := middleware.PrincipalFromCtx(c)
+	if !ok || !principal.IsEmployee() {
 		return c.Status(fiber.StatusUnauthorized).JSON(nil)
 	}
 
@@ -101,18 +103,71 @@ func (h *RoleHandler) deleteRole(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
 }
 
-func RoleRoute(db *sqlx.DB, public fiber.Router, protected fiber.Router) models.RoleRepository {
+func (h *RoleHandler) getPermissions(c *fiber.Ctx) error {
+	return c.JSON(models.NewPermissionInfos())
+}
+
+func (h *RoleHandler) getRolePermissions(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		res := models.NewErrorResponse(errors.New("invalid id"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	permissions, err := h.service.GetPermissions(c.Context(), id)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.JSON(permissions)
+}
+
+func (h *RoleHandler) setRolePermissions(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		res := models.NewErrorResponse(errors.New("invalid id"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	var body struct {
+		Permissions []models.Permission `json:"permissions"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Permissions == nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	permissions, err := h.service.SetPermissions(c.Context(), id, body.Permissions)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		switch {
+		case errors.Is(err, models.ErrInvalidPermission):
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		case errors.Is(err, sql.ErrNoRows):
+			return c.Status(fiber.StatusNotFound).JSON(res)
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(res)
+		}
+	}
+
+	return c.JSON(permissions)
+}
+
+func RoleRoute(db *sqlx.DB, protected fiber.Router, timeout time.Duration) models.RoleRepository {
 	repo := repository.NewRoleRepository(db)
-	service := services.NewRoleService(repo, 10*time.Second)
+	service := services.NewRoleService(repo, timeout)
 	handler := NewRoleHandler(service)
 
-	// OPEN
-	public.Post("/roles", handler.createRole)
 	// ONLY WITH JWT
-	protected.Get("/roles", handler.getRoles)
-	protected.Get("/roles/:id", handler.getRoleById)
-	protected.Put("/roles/:id", handler.updateRole)
-	protected.Delete("/roles/:id", handler.deleteRole)
+	protected.Post("/roles", middleware.Require(models.PermRoleWrite), handler.createRole)
+	protected.Get("/roles", middleware.Require(models.PermRoleRead), handler.getRoles)
+	protected.Get("/roles/:id", middleware.Require(models.PermRoleRead), handler.getRoleById)
+	protected.Put("/roles/:id", middleware.Require(models.PermRoleWrite), handler.updateRole)
+	protected.Delete("/roles/:id", middleware.Require(models.PermRoleWrite), handler.deleteRole)
+	protected.Get("/roles/:id/permissions", middleware.Require(models.PermRoleRead), handler.getRolePermissions)
+	protected.Put("/roles/:id/permissions", middleware.Require(models.PermRoleWrite), handler.setRolePermissions)
+	protected.Get("/permissions", middleware.Require(models.PermRoleRead), handler.getPermissions)
 
 	return repo
 }
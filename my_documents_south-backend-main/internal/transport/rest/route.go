@@ -1,23 +1,111 @@
 package rest
 
 import (
+	"my_documents_south_backend/internal/config"
+	"my_documents_south_backend/internal/mailer"
 	"my_documents_south_backend/internal/middleware"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/sms"
+	"my_documents_south_backend/internal/storage"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jmoiron/sqlx"
 )
 
-func Setup(db *sqlx.DB, app *fiber.App) {
+func Setup(
+	db *sqlx.DB,
+	app *fiber.App,
+	cfg *config.Config,
+	store storage.BlobStore,
+	mail mailer.Mailer,
+	smsProvider sms.SMSProvider,
+) {
 	publicRouter := app.Group("/pub")
 
+	activeSession := middleware.ActiveSession(repository.NewSessionRepository(db), cfg.Timeouts.Auth)
+
 	protectedRouter := app.Group("/prot")
-	protectedRouter.Use(middleware.Protected())
-
-	roleRepository := RoleRoute(db, publicRouter, protectedRouter)
-	tariffRepository := TariffRoute(db, publicRouter, protectedRouter)
-	employeeRepository := EmployeeRoute(db, publicRouter, protectedRouter, roleRepository)
-	userRepository := UserRoute(db, publicRouter, protectedRouter, tariffRepository)
-	RequestRoute(db, protectedRouter, userRepository, employeeRepository)
-	ServiceRoute(db, protectedRouter)
-	AuthRouter(publicRouter, protectedRouter, userRepository, employeeRepository)
+	protectedRouter.Use(middleware.Protected(cfg.JWT.Secret))
+	protectedRouter.Use(activeSession)
+	roleService := services.NewRoleService(repository.NewRoleRepository(db), cfg.Timeouts.Role)
+	protectedRouter.Use(middleware.Authorize(roleService))
+
+	emailVerification := services.NewEmailVerificationService(
+		repository.NewEmailVerificationRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewEmployeeRepository(db),
+		mail,
+		cfg.EmailVerification,
+		cfg.Timeouts.Auth,
+	)
+
+	settingService := services.NewSettingService(
+		repository.NewSettingRepository(db),
+		repository.NewRoleRepository(db),
+		repository.NewTariffRepository(db),
+		cfg.Storage.MaxUploadSize,
+		cfg.Timeouts.Setting,
+	)
+	SettingRoute(publicRouter, protectedRouter, settingService)
+
+	RoleRoute(db, protectedRouter, cfg.Timeouts.Role)
+	tariffRepository := TariffRoute(db, publicRouter, protectedRouter, cfg.Timeouts.Tariff)
+	employeeRepository := EmployeeRoute(
+		db,
+		publicRouter,
+		protectedRouter,
+		roleService,
+		emailVerification,
+		cfg.Timeouts.Employee,
+	)
+	userRepository := UserRoute(
+		db,
+		publicRouter,
+		protectedRouter,
+		tariffRepository,
+		emailVerification,
+		settingService,
+		cfg.Timeouts.User,
+	)
+	requestRepository := RequestRoute(
+		db,
+		protectedRouter,
+		userRepository,
+		employeeRepository,
+		emailVerification,
+		settingService,
+		cfg.Timeouts.Request,
+	)
+	DocumentRoute(
+		db,
+		protectedRouter,
+		requestRepository,
+		store,
+		settingService,
+		cfg.Storage.MaxUploadSize,
+		cfg.Storage.AllowedMimeTypes,
+		cfg.Timeouts.Document,
+	)
+	wsRouter := app.Group("/ws", middleware.ProtectedWS(cfg.JWT.Secret), activeSession)
+	ChatRoute(db, wsRouter, protectedRouter, cfg.Timeouts.Chat)
+	ServiceRoute(db, protectedRouter, cfg.Timeouts.Service)
+	SearchRoute(db, protectedRouter, cfg.Timeouts.Search)
+	AuthRouter(
+		db,
+		publicRouter,
+		protectedRouter,
+		userRepository,
+		employeeRepository,
+		smsProvider,
+		emailVerification,
+		cfg.JWT,
+		cfg.OTP,
+		cfg.MFA,
+		cfg.Lockout,
+		cfg.Timeouts.Auth,
+	)
+	EmailVerificationRoute(publicRouter, emailVerification)
+	InviteRoute(db, publicRouter, protectedRouter, roleService, mail, cfg.Invite, cfg.Timeouts.Employee)
+	PasswordResetRoute(db, publicRouter, userRepository, employeeRepository, mail, cfg.PasswordReset, cfg.Timeouts.Auth)
 }
@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/repository/postgres/repository"
+	"my_documents_south_backend/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchHandler struct {
+	searchService models.SearchService
+}
+
+func NewSearchHandler(searchService models.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+func (h *SearchHandler) search(c *fiber.Ctx) error {
+	principal, err := employeePrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid limit"})
+		}
+	}
+
+	result, err := h.searchService.Search(c.Context(), principal, c.Query("q"), limit)
+	if err != nil {
+		res := models.NewErrorResponse(err, c.Path()).Log()
+		if errors.Is(err, models.ErrInvalidSearchQuery) {
+			return c.Status(fiber.StatusBadRequest).JSON(res)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+func SearchRoute(db *sqlx.DB, protected fiber.Router, timeout time.Duration) {
+	service := services.NewSearchService(repository.NewSearchRepository(db), timeout)
+	handler := NewSearchHandler(service)
+
+	protected.Get("/search", handler.search)
+}
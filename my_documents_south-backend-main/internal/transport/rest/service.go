@@ -2,6 +2,7 @@ package rest
 
 import (
 	"errors"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
@@ -96,15 +97,15 @@ func (h *ServiceHandler) deleteService(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
 }
 
-func ServiceRoute(db *sqlx.DB, group fiber.Router) {
+func ServiceRoute(db *sqlx.DB, group fiber.Router, timeout time.Duration) {
 	repo := repository.NewServiceRepository(db)
-	service := services.NewServiceService(repo, 10*time.Second)
+	service := services.NewServiceService(repo, timeout)
 	handler := NewServiceHandler(service)
 
 	tag := group.Group("/services")
-	tag.Post("", handler.createService)
+	tag.Post("", middleware.Require(models.PermServiceWrite), handler.createService)
 	tag.Get("", handler.getServices)
 	tag.Get("/:id", handler.getServiceById)
-	tag.Put("/:id", handler.updateService)
-	tag.Delete("/:id", handler.deleteService)
+	tag.Put("/:id", middleware.Require(models.PermServiceWrite), handler.updateService)
+	tag.Delete("/:id", middleware.Require(models.PermServiceWrite), handler.deleteService)
 }
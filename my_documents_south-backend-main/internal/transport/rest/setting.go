@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"errors"
+	"my_documents_south_backend/internal/middleware"
+	"my_documents_south_backend/internal/models"
+	"my_documents_south_backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SettingHandler struct {
+	settingService *services.SettingService
+}
+
+func NewSettingHandler(settingService *services.SettingService) *SettingHandler {
+	return &SettingHandler{settingService: settingService}
+}
+
+func (h *SettingHandler) getSettings(c *fiber.Ctx) error {
+	settings, err := h.settingService.Get(c.Context())
+	if err != nil {
+		return settingError(c, err)
+	}
+
+	return c.JSON(settings)
+}
+
+func (h *SettingHandler) getPublicSettings(c *fiber.Ctx) error {
+	settings, err := h.settingService.Get(c.Context())
+	if err != nil {
+		return settingError(c, err)
+	}
+
+	return c.JSON(settings.Public())
+}
+
+// updateSettings перезаписывает все настройки, отсутствующие в теле поля получают нулевые значения
+func (h *SettingHandler) updateSettings(c *fiber.Ctx) error {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+
+	var settings models.Settings
+	if err := c.BodyParser(&settings); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.settingService.Update(c.Context(), principal, &settings); err != nil {
+		return settingError(c, err)
+	}
+
+	return c.JSON(settings)
+}
+
+func settingError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	switch {
+	case errors.Is(err, models.ErrInvalidSettings):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	case errors.Is(err, models.ErrForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
+func SettingRoute(public fiber.Router, protected fiber.Router, settingService *services.SettingService) {
+	handler := NewSettingHandler(settingService)
+
+	public.Get("/settings", handler.getPublicSettings)
+	protected.Get("/settings", middleware.Require(models.PermSettingRead), handler.getSettings)
+	protected.Put("/settings", middleware.Require(models.PermSettingWrite), handler.updateSettings)
+}
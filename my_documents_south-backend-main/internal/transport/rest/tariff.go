@@ -2,6 +2,7 @@ package rest
 
 import (
 	"errors"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
@@ -92,16 +93,16 @@ func (h *TariffHandler) deleteTariff(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"id": id})
 }
 
-func TariffRoute(db *sqlx.DB, public fiber.Router, protected fiber.Router) models.TariffRepository {
+func TariffRoute(db *sqlx.DB, public fiber.Router, protected fiber.Router, timeout time.Duration) models.TariffRepository {
 	repo := repository.NewTariffRepository(db)
-	service := services.NewTariffService(repo, 10*time.Second)
+	service := services.NewTariffService(repo, timeout)
 	handler := NewTariffHandler(service)
 
-	public.Post("/tariffs", handler.createTariff)
+	protected.Post("/tariffs", middleware.Require(models.PermTariffWrite), handler.createTariff)
 	protected.Get("/tariffs", handler.getTariffs)
 	protected.Get("/tariffs/:id", handler.getTariffById)
-	protected.Put("/tariffs/:id", handler.updateTariff)
-	protected.Delete("/tariffs/:id", handler.deleteTariff)
+	protected.Put("/tariffs/:id", middleware.Require(models.PermTariffWrite), handler.updateTariff)
+	protected.Delete("/tariffs/:id", middleware.Require(models.PermTariffWrite), handler.deleteTariff)
 
 	return repo
 }
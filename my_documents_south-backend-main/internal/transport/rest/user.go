@@ -2,9 +2,13 @@ package rest
 
 import (
 	"errors"
+	"log"
+	"my_documents_south_backend/internal/middleware"
 	"my_documents_south_backend/internal/models"
 	"my_documents_south_backend/internal/repository/postgres/repository"
 	"my_documents_south_backend/internal/services"
+	"my_documents_south_backend/internal/utils/password"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,11 +16,12 @@ import (
 )
 
 type UserHandler struct {
-	userService models.UserService
+	userService       models.UserService
+	emailVerification *services.EmailVerificationService
 }
 
-func NewUserHandler(userService models.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService models.UserService, emailVerification *services.EmailVerificationService) *UserHandler {
+	return &UserHandler{userService: userService, emailVerification: emailVerification}
 }
 
 func (h *UserHandler) createUser(c *fiber.Ctx) error {
@@ -29,8 +34,17 @@ func (h *UserHandler) createUser(c *fiber.Ctx) error {
 
 	err := h.userService.Create(c.Context(), &user)
 	if err != nil {
-		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(fiber.StatusConflict).JSON(res)
+		if errors.Is(err, models.ErrSignupClosed) {
+			res := models.NewErrorResponse(err, c.Path()).Log()
+			return c.Status(fiber.StatusForbidden).JSON(res)
+		}
+		return userError(c, err)
+	}
+
+	// аккаунт уже создан: при ошибке письмо можно запросить повторно
+	subject := models.Actor{Type: models.ActorUser, Id: user.Id}
+	if err := h.emailVerification.Send(c.Context(), subject, user.Email); err != nil {
+		log.Printf("user %d: failed to send email verification: %v", user.Id, err)
 	}
 
 	return c.SendStatus(fiber.StatusCreated)
@@ -57,6 +71,84 @@ func (h *UserHandler) getUserById(c *fiber.Ctx) error {
 	return c.JSON(user)
 }
 
+func (h *UserHandler) getMe(c *fiber.Ctx) error {
+	principal, err := userPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.userService.GetById(c.Context(), int(principal.Id))
+	if err != nil {
+		return userError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+func (h *UserHandler) patchMe(c *fiber.Ctx) error {
+	principal, err := userPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	return h.patch(c, principal.Id)
+}
+
+func (h *UserHandler) patchUser(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		res := models.NewErrorResponse(errors.New("invalid id"), c.Path()).Log()
+		return c.Status(fiber.StatusBadRequest).JSON(res)
+	}
+
+	return h.patch(c, id)
+}
+
+// patch меняет переданные поля клиента. При смене почты отправляется письмо для её подтверждения
+func (h *UserHandler) patch(c *fiber.Ctx, id int64) error {
+	var update models.UserUpdate
+	if err := c.BodyParser(&update); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	user, err := h.userService.Patch(c.Context(), id, update)
+	if err != nil {
+		return userError(c, err)
+	}
+
+	if user.EmailVerifiedAt == nil && update.Email != nil {
+		subject := models.Actor{Type: models.ActorUser, Id: user.Id}
+		if err := h.emailVerification.Send(c.Context(), subject, user.Email); err != nil {
+			log.Printf("user %d: failed to send email verification: %v", user.Id, err)
+		}
+	}
+
+	return c.JSON(user)
+}
+
+func (h *UserHandler) changePassword(c *fiber.Ctx) error {
+	principal, err := userPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		res := models.NewErrorResponse(errors.New("invalid body"), c.Path()).Log()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	}
+
+	if err := h.userService.ChangePassword(c.Context(), principal, body.CurrentPassword, body.NewPassword); err != nil {
+		return userError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (h *UserHandler) deleteUser(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
@@ -66,12 +158,7 @@ func (h *UserHandler) deleteUser(c *fiber.Ctx) error {
 
 	err = h.userService.Delete(c.Context(), id)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if err.Error() == "user not found" {
-			status = fiber.StatusNotFound
-		}
-		res := models.NewErrorResponse(err, c.Path()).Log()
-		return c.Status(status).JSON(res)
+		return userError(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -79,15 +166,59 @@ func (h *UserHandler) deleteUser(c *fiber.Ctx) error {
 	})
 }
 
-func UserRoute(db *sqlx.DB, public fiber.Router, protected fiber.Router, tariffRepo models.TariffRepository) models.UserRepository {
+// userPrincipal возвращает клиента из токена. При ошибке ответ уже отправлен
+func userPrincipal(c *fiber.Ctx) (*models.Principal, error) {
+	principal, err := principalFromCtx(c)
+	if err != nil {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(err, c.Path()).Log())
+	}
+	if principal.IsEmployee() {
+		res := models.NewErrorResponse(models.ErrForbidden, c.Path()).Log()
+		return nil, c.Status(fiber.StatusForbidden).JSON(res)
+	}
+	return principal, nil
+}
+
+// userError подбирает HTTP статус для ошибок изменения клиентов
+func userError(c *fiber.Ctx, err error) error {
+	res := models.NewErrorResponse(err, c.Path()).Log()
+
+	switch {
+	case errors.Is(err, models.ErrInvalidUser), errors.Is(err, password.ErrInvalidPassword):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	case errors.Is(err, models.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(res)
+	case errors.Is(err, models.ErrWrongPassword):
+		return c.Status(fiber.StatusForbidden).JSON(res)
+	case errors.Is(err, models.ErrUserExists):
+		return c.Status(fiber.StatusConflict).JSON(res)
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(res)
+	}
+}
+
+func UserRoute(
+	db *sqlx.DB,
+	public fiber.Router,
+	protected fiber.Router,
+	tariffRepo models.TariffRepository,
+	emailVerification *services.EmailVerificationService,
+	settings *services.SettingService,
+	timeout time.Duration,
+) models.UserRepository {
 	userRepo := repository.NewUserRepository(db)
-	service := services.NewUserService(userRepo, tariffRepo, 10*time.Second)
-	handler := NewUserHandler(service)
+	service := services.NewUserService(userRepo, tariffRepo, repository.NewSessionRepository(db), settings, timeout)
+	handler := NewUserHandler(service, emailVerification)
 
 	public.Post("/users/signup", handler.createUser)
-	protected.Get("/users/", handler.getUsers)
-	protected.Get("/users/:id", handler.getUserById)
-	protected.Delete("/users/:id", handler.deleteUser)
+	// /users/me регистрируется раньше /users/:id
+	protected.Get("/users/me", handler.getMe)
+	protected.Patch("/users/me", handler.patchMe)
+	protected.Post("/users/me/password", handler.changePassword)
+	protected.Get("/users/", middleware.Require(models.PermUserReadAll), handler.getUsers)
+	protected.Get("/users/:id", middleware.Require(models.PermUserReadAll), handler.getUserById)
+	protected.Patch("/users/:id", middleware.Require(models.PermUserWrite), handler.patchUser)
+	protected.Delete("/users/:id", middleware.Require(models.PermUserWrite), handler.deleteUser)
 
 	return userRepo
 }
@@ -0,0 +1,38 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+var ErrInvalidPassword = errors.New("invalid password")
+
+// maxLength bcrypt учитывает только первые 72 байта пароля
+const maxLength = 72
+
+// Validate проверяет требования к паролю: не короче 6 символов, не длиннее 72 байт,
+// хотя бы одна буква и одна цифра
+func Validate(password string) error {
+	if len(password) < 6 {
+		return fmt.Errorf("%w: must contain at least 6 characters", ErrInvalidPassword)
+	}
+	if len(password) > maxLength {
+		return fmt.Errorf("%w: must not exceed 72 bytes", ErrInvalidPassword)
+	}
+
+	hasLetter := false
+	hasDigit := false
+	for _, ch := range password {
+		if unicode.IsLetter(ch) {
+			hasLetter = true
+		}
+		if unicode.IsDigit(ch) {
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return fmt.Errorf("%w: must contain at least one letter and one digit", ErrInvalidPassword)
+	}
+	return nil
+}
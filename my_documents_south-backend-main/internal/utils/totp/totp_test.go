@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret ключ "12345678901234567890" из тестовых векторов RFC 6238 в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCode векторы SHA1 из приложения B RFC 6238, последние 6 цифр 8-значных кодов
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error = %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code() with invalid secret error = nil")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(step int64) string {
+		value, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		skew     int
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", rfcSecret, code(step), 1, step, true},
+		{"previous step within skew", rfcSecret, code(step - 1), 1, step - 1, true},
+		{"next step within skew", rfcSecret, code(step + 1), 1, step + 1, true},
+		{"outside skew", rfcSecret, code(step - 2), 1, 0, false},
+		{"previous step without skew", rfcSecret, code(step - 1), 0, 0, false},
+		{"lowercase secret", strings.ToLower(rfcSecret), code(step), 0, step, true},
+		{"wrong code", rfcSecret, "000000", 1, 0, false},
+		{"short code", rfcSecret, code(step)[:5], 1, 0, false},
+		{"long code", rfcSecret, code(step) + "0", 1, 0, false},
+		{"empty code", rfcSecret, "", 1, 0, false},
+		{"invalid secret", "not base32!", code(step), 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(tt.secret, tt.code, now, tt.skew)
+			if ok != tt.wantOk || gotStep != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret size = %d, want %d", len(key), secretSize)
+	}
+
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Error("GenerateSecret() returned the same secret twice")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("My Documents", "name@example.com", rfcSecret)
+	want := "otpauth://totp/My%20Documents:name@example.com?algorithm=SHA1&digits=6&issuer=My+Documents&period=30&secret=" + rfcSecret
+	if uri != want {
+		t.Errorf("URI() = %s, want %s", uri, want)
+	}
+}
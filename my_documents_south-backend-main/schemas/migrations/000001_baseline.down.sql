@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS "setting";
+DROP TABLE IF EXISTS "request";
+DROP TABLE IF EXISTS "employee_specs";
+DROP TABLE IF EXISTS "employee";
+DROP TABLE IF EXISTS "service";
+DROP TABLE IF EXISTS "user";
+DROP TABLE IF EXISTS "tariff";
+DROP TABLE IF EXISTS "role";
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "request_event" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"request_id" BIGINT NOT NULL REFERENCES "request" ON UPDATE CASCADE ON DELETE CASCADE,
+	"type" CHARACTER VARYING(32) NOT NULL,
+	"actor_type" CHARACTER VARYING(16) NOT NULL,
+	"actor_id" BIGINT NOT NULL,
+	"old_value" TEXT,
+	"new_value" TEXT,
+	"comment" TEXT,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "request_event_request_id_idx" ON "request_event" ("request_id", "created_at", "id");
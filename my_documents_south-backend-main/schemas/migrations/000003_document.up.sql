@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "document" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"request_id" BIGINT NOT NULL REFERENCES "request" ON UPDATE CASCADE ON DELETE CASCADE,
+	"name" CHARACTER VARYING(255) NOT NULL,
+	"mime_type" CHARACTER VARYING(255) NOT NULL,
+	"size" BIGINT NOT NULL,
+	"checksum" CHARACTER(64) NOT NULL,
+	"storage_key" TEXT NOT NULL UNIQUE,
+	"uploaded_by_type" CHARACTER VARYING(16) NOT NULL,
+	"uploaded_by_id" BIGINT NOT NULL,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "document_request_id_idx" ON "document" ("request_id");
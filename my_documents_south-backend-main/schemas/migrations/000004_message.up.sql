@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "message" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"request_id" BIGINT NOT NULL REFERENCES "request" ON UPDATE CASCADE ON DELETE CASCADE,
+	"sender_type" CHARACTER VARYING(16) NOT NULL,
+	"sender_id" BIGINT NOT NULL,
+	"body" TEXT NOT NULL,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	"read_at" TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS "message_request_id_idx" ON "message" ("request_id", "id");
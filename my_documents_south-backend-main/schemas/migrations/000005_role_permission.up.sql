@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS "role_permission" (
+	"role_id" INT NOT NULL REFERENCES "role" ON UPDATE CASCADE ON DELETE CASCADE,
+	"permission" CHARACTER VARYING(64) NOT NULL,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY ("role_id", "permission")
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "refresh_token" (
+	"id" CHARACTER VARYING(64) NOT NULL PRIMARY KEY,
+	"family_id" CHARACTER VARYING(64) NOT NULL,
+	"subject_type" CHARACTER VARYING(16) NOT NULL,
+	"subject_id" BIGINT NOT NULL,
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"rotated_at" TIMESTAMPTZ,
+	"revoked_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "refresh_token_family_id_idx" ON "refresh_token" ("family_id");
+CREATE INDEX IF NOT EXISTS "refresh_token_subject_idx" ON "refresh_token" ("subject_type", "subject_id");
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS "session" (
+	"id" CHARACTER VARYING(64) NOT NULL PRIMARY KEY,
+	"subject_type" CHARACTER VARYING(16) NOT NULL,
+	"subject_id" BIGINT NOT NULL,
+	"user_agent" TEXT NOT NULL DEFAULT '',
+	"ip" CHARACTER VARYING(64) NOT NULL DEFAULT '',
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"last_used_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	"revoked_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "session_subject_idx" ON "session" ("subject_type", "subject_id");
+
+-- Сессии для refresh токенов, выданных до появления таблицы
+INSERT INTO "session" ("id", "subject_type", "subject_id", "expires_at", "last_used_at", "revoked_at", "created_at")
+SELECT
+	t."family_id",
+	MIN(t."subject_type"),
+	MIN(t."subject_id"),
+	MAX(t."expires_at"),
+	MAX(t."created_at"),
+	CASE WHEN bool_and(t."revoked_at" IS NOT NULL) THEN MAX(t."revoked_at") END,
+	MIN(t."created_at")
+FROM "refresh_token" t
+GROUP BY t."family_id"
+ON CONFLICT ("id") DO NOTHING;
+
+ALTER TABLE "refresh_token"
+	ADD CONSTRAINT "refresh_token_family_id_fkey"
+	FOREIGN KEY ("family_id") REFERENCES "session" ON UPDATE CASCADE ON DELETE CASCADE;
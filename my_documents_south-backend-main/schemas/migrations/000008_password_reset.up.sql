@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "password_reset" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"subject_type" CHARACTER VARYING(16) NOT NULL,
+	"subject_id" BIGINT NOT NULL,
+	"token_hash" CHARACTER(64) NOT NULL UNIQUE,
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"used_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "password_reset_subject_idx" ON "password_reset" ("subject_type", "subject_id", "created_at");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "otp_code";
+
+ALTER TABLE "user" DROP COLUMN IF EXISTS "phone_verified_at";
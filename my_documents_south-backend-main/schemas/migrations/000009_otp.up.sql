@@ -0,0 +1,14 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "phone_verified_at" TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS "otp_code" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"phone" CHARACTER VARYING(32) NOT NULL,
+	"purpose" CHARACTER VARYING(16) NOT NULL,
+	"code_hash" CHARACTER(64) NOT NULL,
+	"attempts" INTEGER NOT NULL DEFAULT 0,
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"used_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "otp_code_phone_idx" ON "otp_code" ("phone", "purpose", "created_at");
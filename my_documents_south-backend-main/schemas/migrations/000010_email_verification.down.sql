@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "email_verification";
+
+ALTER TABLE "employee" DROP COLUMN IF EXISTS "email_verified_at";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "email_verified_at";
@@ -0,0 +1,15 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "email_verified_at" TIMESTAMPTZ;
+ALTER TABLE "employee" ADD COLUMN IF NOT EXISTS "email_verified_at" TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS "email_verification" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"subject_type" CHARACTER VARYING(16) NOT NULL,
+	"subject_id" BIGINT NOT NULL,
+	"email" CHARACTER VARYING(255) NOT NULL,
+	"token_hash" CHARACTER(64) NOT NULL UNIQUE,
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"used_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "email_verification_subject_idx" ON "email_verification" ("subject_type", "subject_id", "created_at");
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "mfa_challenge";
+DROP TABLE IF EXISTS "employee_recovery_code";
+DROP TABLE IF EXISTS "employee_totp";
+
+ALTER TABLE "role" DROP COLUMN IF EXISTS "mfa_required";
@@ -0,0 +1,30 @@
+ALTER TABLE "role" ADD COLUMN IF NOT EXISTS "mfa_required" BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS "employee_totp" (
+	"employee_id" BIGINT NOT NULL PRIMARY KEY REFERENCES "employee" ON UPDATE CASCADE ON DELETE CASCADE,
+	"secret" CHARACTER VARYING(64) NOT NULL,
+	"confirmed_at" TIMESTAMPTZ,
+	"last_used_step" BIGINT NOT NULL DEFAULT 0,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "employee_recovery_code" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"employee_id" BIGINT NOT NULL REFERENCES "employee" ON UPDATE CASCADE ON DELETE CASCADE,
+	"code_hash" CHARACTER(64) NOT NULL,
+	"used_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE ("employee_id", "code_hash")
+);
+
+CREATE TABLE IF NOT EXISTS "mfa_challenge" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"employee_id" BIGINT NOT NULL REFERENCES "employee" ON UPDATE CASCADE ON DELETE CASCADE,
+	"token_hash" CHARACTER(64) NOT NULL UNIQUE,
+	"attempts" INTEGER NOT NULL DEFAULT 0,
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"used_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "mfa_challenge_employee_id_idx" ON "mfa_challenge" ("employee_id");
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS "login_attempt" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"key" CHARACTER VARYING(320) NOT NULL,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "login_attempt_key_idx" ON "login_attempt" ("key", "created_at");
+CREATE INDEX IF NOT EXISTS "login_attempt_created_at_idx" ON "login_attempt" ("created_at");
+
+CREATE TABLE IF NOT EXISTS "login_lockout" (
+	"key" CHARACTER VARYING(320) NOT NULL PRIMARY KEY,
+	"locked_until" TIMESTAMPTZ NOT NULL,
+	"level" INTEGER NOT NULL,
+	"updated_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "audit_log" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"type" CHARACTER VARYING(64) NOT NULL,
+	"actor_type" CHARACTER VARYING(16),
+	"actor_id" BIGINT,
+	"ip" CHARACTER VARYING(64),
+	"details" JSONB NOT NULL DEFAULT '{}',
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "audit_log_type_idx" ON "audit_log" ("type", "created_at");
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "employee_invite" (
+	"id" BIGSERIAL NOT NULL PRIMARY KEY,
+	"email" CHARACTER VARYING(255) NOT NULL,
+	"role_id" INTEGER NOT NULL REFERENCES "role" ON UPDATE CASCADE ON DELETE CASCADE,
+	"name" CHARACTER VARYING(100),
+	"last_name" CHARACTER VARYING(100),
+	"middle_name" CHARACTER VARYING(100),
+	"token_hash" CHARACTER(64) NOT NULL UNIQUE,
+	"invited_by" BIGINT REFERENCES "employee" ON UPDATE CASCADE ON DELETE SET NULL,
+	"employee_id" BIGINT REFERENCES "employee" ON UPDATE CASCADE ON DELETE SET NULL,
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"accepted_at" TIMESTAMPTZ,
+	"revoked_at" TIMESTAMPTZ,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "employee_invite_email_idx" ON "employee_invite" (LOWER("email"));
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "setting_singleton_idx";
@@ -0,0 +1,15 @@
+-- раньше каждая установка суперроли или тарифа по умолчанию добавляла строку, оставляем одну
+UPDATE "setting" s SET
+	"default_tariff_id" = COALESCE(s."default_tariff_id", (
+		SELECT "default_tariff_id" FROM "setting" WHERE "default_tariff_id" IS NOT NULL ORDER BY "id" LIMIT 1
+	)),
+	"superuser_role_id" = COALESCE(s."superuser_role_id", (
+		SELECT "superuser_role_id" FROM "setting" WHERE "superuser_role_id" IS NOT NULL ORDER BY "id" LIMIT 1
+	))
+WHERE s."id" = (SELECT MIN("id") FROM "setting");
+
+DELETE FROM "setting" WHERE "id" <> (SELECT MIN("id") FROM "setting");
+
+INSERT INTO "setting" ("created_at") SELECT CURRENT_TIMESTAMP WHERE NOT EXISTS (SELECT 1 FROM "setting");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "setting_singleton_idx" ON "setting" ((TRUE));
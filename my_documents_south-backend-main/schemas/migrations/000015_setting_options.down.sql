@@ -0,0 +1,7 @@
+ALTER TABLE "setting"
+	DROP COLUMN IF EXISTS "signup_open",
+	DROP COLUMN IF EXISTS "max_upload_size",
+	DROP COLUMN IF EXISTS "sla_default_priority",
+	DROP COLUMN IF EXISTS "sla_resolution_hours",
+	DROP COLUMN IF EXISTS "support_email",
+	DROP COLUMN IF EXISTS "support_phone";
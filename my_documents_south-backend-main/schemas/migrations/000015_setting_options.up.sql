@@ -0,0 +1,7 @@
+ALTER TABLE "setting"
+	ADD COLUMN IF NOT EXISTS "signup_open" BOOLEAN NOT NULL DEFAULT TRUE,
+	ADD COLUMN IF NOT EXISTS "max_upload_size" BIGINT NOT NULL DEFAULT 20971520 CHECK ("max_upload_size" > 0),
+	ADD COLUMN IF NOT EXISTS "sla_default_priority" SMALLINT NOT NULL DEFAULT 0 CHECK ("sla_default_priority" >= 0),
+	ADD COLUMN IF NOT EXISTS "sla_resolution_hours" INT NOT NULL DEFAULT 72 CHECK ("sla_resolution_hours" > 0),
+	ADD COLUMN IF NOT EXISTS "support_email" TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS "support_phone" TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS "request_owner_id_idx";
+
+-- владельцем снова становится сотрудник, создавший заявку. Заявки, созданные клиентами, сотрудника не имеют
+DO $$
+DECLARE
+	orphaned BIGINT;
+BEGIN
+	SELECT COUNT(*) INTO orphaned FROM "request" WHERE "created_by_employee_id" IS NULL;
+	IF orphaned > 0 THEN
+		RAISE EXCEPTION '% requests were created by clients and cannot be owned by an employee', orphaned;
+	END IF;
+END $$;
+
+ALTER TABLE "request" DROP CONSTRAINT IF EXISTS "request_owner_id_fkey";
+UPDATE "request" SET "owner_id" = "created_by_employee_id";
+ALTER TABLE "request" ADD CONSTRAINT "request_owner_id_fkey"
+	FOREIGN KEY ("owner_id") REFERENCES "employee" ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "request" DROP COLUMN IF EXISTS "created_by_employee_id";
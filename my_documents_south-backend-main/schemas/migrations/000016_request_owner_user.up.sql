@@ -0,0 +1,36 @@
+-- владелец заявки — клиент, а не сотрудник. До миграции owner_id ссылался на сотрудника: он становится
+-- автором заявки created_by_employee_id, а владелец-клиент берётся из таблицы "request_owner_map"
+-- (request_id, user_id), которую нужно заполнить перед миграцией. Без сопоставления id сотрудника
+-- совпал бы с id случайного клиента и открыл бы ему заявку, поэтому миграция прерывается
+ALTER TABLE "request"
+	ADD COLUMN IF NOT EXISTS "created_by_employee_id" BIGINT REFERENCES "employee" ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE "request" r SET "created_by_employee_id" = r."owner_id"
+WHERE EXISTS (SELECT 1 FROM "employee" e WHERE e."id" = r."owner_id");
+
+ALTER TABLE "request" DROP CONSTRAINT IF EXISTS "request_owner_id_fkey";
+
+DO $$
+DECLARE
+	unmapped BIGINT;
+BEGIN
+	IF to_regclass('"request_owner_map"') IS NULL THEN
+		SELECT COUNT(*) INTO unmapped FROM "request";
+	ELSE
+		EXECUTE 'UPDATE "request" r SET "owner_id" = m."user_id" FROM "request_owner_map" m WHERE m."request_id" = r."id"';
+		EXECUTE 'SELECT COUNT(*) FROM "request" r
+				 WHERE NOT EXISTS (SELECT 1 FROM "request_owner_map" m WHERE m."request_id" = r."id")'
+			INTO unmapped;
+	END IF;
+
+	IF unmapped > 0 THEN
+		RAISE EXCEPTION '% requests have no client owner: fill "request_owner_map" (request_id, user_id) and rerun the migration', unmapped;
+	END IF;
+END $$;
+
+ALTER TABLE "request" ADD CONSTRAINT "request_owner_id_fkey"
+	FOREIGN KEY ("owner_id") REFERENCES "user" ON UPDATE CASCADE ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS "request_owner_map";
+
+CREATE INDEX IF NOT EXISTS "request_owner_id_idx" ON "request" ("owner_id");
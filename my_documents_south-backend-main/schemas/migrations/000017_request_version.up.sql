@@ -0,0 +1,2 @@
+-- версия заявки для оптимистичной блокировки: увеличивается при каждом изменении
+ALTER TABLE "request" ADD COLUMN IF NOT EXISTS "version" INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS "user_snils_trgm_idx";
+DROP INDEX IF EXISTS "user_inn_trgm_idx";
+DROP INDEX IF EXISTS "user_phone_trgm_idx";
+DROP INDEX IF EXISTS "user_full_name_trgm_idx";
+
+DROP INDEX IF EXISTS "message_search_idx";
+ALTER TABLE "message" DROP COLUMN IF EXISTS "search";
+
+DROP INDEX IF EXISTS "request_search_idx";
+ALTER TABLE "request" DROP COLUMN IF EXISTS "search";
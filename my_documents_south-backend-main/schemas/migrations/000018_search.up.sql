@@ -0,0 +1,19 @@
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+-- полнотекстовый поиск по заявкам и сообщениям, название заявки весит больше описания
+ALTER TABLE "request" ADD COLUMN IF NOT EXISTS "search" TSVECTOR
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', "name"), 'A') || setweight(to_tsvector('russian', "desc"), 'B')
+	) STORED;
+CREATE INDEX IF NOT EXISTS "request_search_idx" ON "request" USING GIN ("search");
+
+ALTER TABLE "message" ADD COLUMN IF NOT EXISTS "search" TSVECTOR
+	GENERATED ALWAYS AS (to_tsvector('russian', "body")) STORED;
+CREATE INDEX IF NOT EXISTS "message_search_idx" ON "message" USING GIN ("search");
+
+-- поиск клиентов по фрагменту ФИО, телефона, ИНН и СНИЛС
+CREATE INDEX IF NOT EXISTS "user_full_name_trgm_idx" ON "user"
+	USING GIN ((LOWER("last_name" || ' ' || "name" || ' ' || COALESCE("middle_name", ''))) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "user_phone_trgm_idx" ON "user" USING GIN ("phone" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "user_inn_trgm_idx" ON "user" USING GIN ("inn" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "user_snils_trgm_idx" ON "user" USING GIN ("snils" gin_trgm_ops);